@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -43,10 +44,35 @@ type AvalanchegoSpec struct {
 	// +optional
 	BootstrapperURL string `json:"bootstrapperURL,omitempty"`
 
+	// Beacons the other nodes bootstrap from: nodes of this deployment, and external nodes.
+	// Nodes wait for a quorum of them, bootstrappers bootstrap from the other bootstrappers.
+	// Node 0 by default, can't be combined with bootstrapperURL.
+	// +optional
+	Bootstrappers []BootstrapperSpec `json:"bootstrappers,omitempty"`
+
+	// Network the nodes run: mainnet, fuji, local, or a custom network with its own genesis.
+	// Defaults to the network of networkID.
+	// +optional
+	Network NetworkPreset `json:"network,omitempty"`
+
+	// ID of the network, given by network unless it is custom. Defaults to 12346 for custom networks.
+	// Generated genesis files use it. Can't be changed once set.
+	// +optional
+	NetworkID uint32 `json:"networkID,omitempty"`
+
 	// Genesis for nodes, that will be attached to existing network
 	// +optional
 	Genesis string `json:"genesis,omitempty"`
 
+	// Genesis generated for a new custom network, the nodes being its initial stakers.
+	// Fields which are not given keep the values of the default genesis. Can't be combined with genesis.
+	// +optional
+	GenesisTemplate *GenesisTemplate `json:"genesisTemplate,omitempty"`
+
+	// C-Chain of the generated genesis, and config of the C-Chain of the nodes
+	// +optional
+	CChain *CChainSpec `json:"cChain,omitempty"`
+
 	// Predefined secrets for nodes, quantity, should correlate to nodeCount
 	// +optional
 	ExistingSecrets []string `json:"existingSecrets,omitempty"`
@@ -84,13 +110,649 @@ type AvalanchegoSpec struct {
 	//Specify Annotations for Avalangego pods
 	// +optional
 	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// What happens to the PVC and the Secret of a node removed by lowering nodeCount.
+	// Retained ones are reused if nodeCount is raised again, and deleted together with the Avalanchego object.
+	// +optional
+	// +kubebuilder:default:="Retain"
+	StorageRetentionPolicy StorageRetentionPolicy `json:"storageRetentionPolicy,omitempty"`
+
+	// nodeCount can't be lowered below this number of nodes
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinValidators int `json:"minValidators,omitempty"`
+
+	// What happens to the data of the nodes when the Avalanchego object is deleted
+	// +optional
+	Teardown TeardownSpec `json:"teardown,omitempty"`
+
+	// Delete existingSecrets together with the nodes, as if they were created by the operator
+	// +optional
+	AdoptSecrets bool `json:"adoptSecrets,omitempty"`
+
+	// Storage of the database of every node
+	// +optional
+	Storage StorageSpec `json:"storage,omitempty"`
+
+	// Scheduled VolumeSnapshots of the PVC of a node
+	// +optional
+	Backup *BackupSpec `json:"backup,omitempty"`
+
+	// Data the database of new nodes starts from, instead of bootstrapping from scratch.
+	// Only applies to nodes whose PVC is created after it is set.
+	// +optional
+	DataSource *DataSourceSpec `json:"dataSource,omitempty"`
+
+	// How a new image or tag is rolled out to the nodes
+	// +optional
+	UpgradeStrategy UpgradeStrategy `json:"upgradeStrategy,omitempty"`
+
+	// Thresholds of the probes of the avalanchego container
+	// +optional
+	Probes ProbesSpec `json:"probes,omitempty"`
+}
+
+// BootstrapperSpec is a beacon: either a node of this deployment, or an external node
+type BootstrapperSpec struct {
+	// Index of a node of this deployment
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Node *int `json:"node,omitempty"`
+
+	// Staking address of an external node, as host:port. Hostnames are resolved by the operator.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// NodeID of the external node, e.g. NodeID-7Xhw2mDxuDS44j42TCB6U5579esbSt3Lg
+	// +optional
+	NodeID string `json:"nodeID,omitempty"`
+}
+
+// ProbesSpec overrides the thresholds of the default probes of the avalanchego container
+type ProbesSpec struct {
+	// TCP check of the HTTP port, liveness and readiness are only probed once it succeeds.
+	// Every 10s, failing after 60 attempts by default.
+	// +optional
+	Startup ProbeSpec `json:"startup,omitempty"`
+
+	// GET of /ext/health, every 10s, failing after 3 attempts by default.
+	// Pods are also only ready once the operator found them bootstrapped, with info.isBootstrapped.
+	// +optional
+	Readiness ProbeSpec `json:"readiness,omitempty"`
+
+	// TCP check of the HTTP port, every 30s, failing after 5 attempts by default
+	// +optional
+	Liveness ProbeSpec `json:"liveness,omitempty"`
+}
+
+// ProbeSpec overrides the thresholds of a probe, the defaults are kept for the ones not given
+type ProbeSpec struct {
+	// Don't probe at all
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// UpgradeStrategyType tells in which order the nodes are upgraded
+// +kubebuilder:validation:Enum=RollingUpdate;Canary
+type UpgradeStrategyType string
+
+const (
+	// RollingUpdateStrategy upgrades maxUnavailable nodes at a time, in order of their index
+	RollingUpdateStrategy UpgradeStrategyType = "RollingUpdate"
+	// CanaryStrategy upgrades canary.nodes nodes first, then the other nodes like RollingUpdate
+	CanaryStrategy UpgradeStrategyType = "Canary"
+)
+
+// UpgradeFailurePolicy tells what happens when an upgraded node doesn't become healthy
+// +kubebuilder:validation:Enum=Pause;Rollback
+type UpgradeFailurePolicy string
+
+const (
+	// PauseOnFailure stops upgrading nodes, the upgraded ones are left as they are
+	PauseOnFailure UpgradeFailurePolicy = "Pause"
+	// RollbackOnFailure reverts the upgraded nodes to the previous image
+	RollbackOnFailure UpgradeFailurePolicy = "Rollback"
+)
+
+// UpgradeStrategy defines how the nodes are upgraded to a new image.
+// A node is only considered upgraded once it is ready, reports healthy through /ext/health and has bootstrapped its chains.
+type UpgradeStrategy struct {
+	// RollingUpdate or Canary
+	// +optional
+	// +kubebuilder:default:="RollingUpdate"
+	Type UpgradeStrategyType `json:"type,omitempty"`
+
+	// Number of nodes upgraded at the same time, 1 if not specified
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxUnavailable int `json:"maxUnavailable,omitempty"`
+
+	// The nodes upgraded first by the Canary strategy
+	// +optional
+	Canary *CanarySpec `json:"canary,omitempty"`
+
+	// Seconds an upgraded node has to become healthy, before the upgrade fails
+	// +optional
+	// +kubebuilder:default:=1800
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// What happens when an upgraded node doesn't become healthy: Pause or Rollback
+	// +optional
+	// +kubebuilder:default:="Pause"
+	FailurePolicy UpgradeFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// CanarySpec defines the nodes upgraded first, before the other nodes
+type CanarySpec struct {
+	// Number of nodes upgraded first, the ones with the lowest indexes
+	// +optional
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=1
+	Nodes int `json:"nodes,omitempty"`
+
+	// Seconds the canary nodes have to stay healthy, before the other nodes are upgraded
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	SoakSeconds int32 `json:"soakSeconds,omitempty"`
+}
+
+// DataSourceSpec defines where the database of a new node comes from. Only one of the sources can be given.
+type DataSourceSpec struct {
+	// Name of a VolumeSnapshot the PVCs are restored from, e.g. one taken by a scheduled backup.
+	// storage.size has to be at least the restore size of the snapshot.
+	// +optional
+	VolumeSnapshot string `json:"volumeSnapshot,omitempty"`
+
+	// Name of a PVC the PVCs are cloned from, in the same namespace and storage class
+	// +optional
+	PersistentVolumeClaim string `json:"persistentVolumeClaim,omitempty"`
+
+	// Database archive downloaded into the volume by an init container, before the node starts
+	// +optional
+	Archive *ArchiveSource `json:"archive,omitempty"`
+}
+
+// ArchiveSource defines a tar archive of a node database, downloaded over HTTP
+type ArchiveSource struct {
+	// HTTP or HTTPS URL of the archive, e.g. a public or presigned URL of an S3-compatible bucket.
+	// Archives ending in .tar.gz or .tgz are decompressed.
+	URL string `json:"url"`
+
+	// Hex encoded SHA-256 checksum of the archive, it is verified before unpacking
+	SHA256 string `json:"sha256"`
+
+	// Image of the init container, it needs sh, curl, sha256sum and tar
+	// +optional
+	// +kubebuilder:default:="curlimages/curl:7.79.1"
+	Image string `json:"image,omitempty"`
+}
+
+// BackupSpec defines when and how the PVC of a node is snapshotted
+type BackupSpec struct {
+	// Cron schedule of the backups, in UTC, e.g. "0 3 * * *" or "@daily"
+	Schedule string `json:"schedule"`
+
+	// Index of the node to back up
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Node int `json:"node,omitempty"`
+
+	// Number of snapshots ready to use kept, older ones are deleted. Failed backups don't count.
+	// +optional
+	// +kubebuilder:default:=3
+	// +kubebuilder:validation:Minimum=1
+	Keep int `json:"keep,omitempty"`
+
+	// VolumeSnapshotClass of the snapshots, the default class is used if empty
+	// +optional
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+
+	// Stop the node until the snapshot is taken, so its database is consistent.
+	// Otherwise the snapshot is taken while the node runs.
+	// +optional
+	StopNode bool `json:"stopNode,omitempty"`
+}
+
+// StorageSpec defines the volume holding the database of a node
+type StorageSpec struct {
+	// Requested size of the PVC of every node, 50Gi if not specified.
+	// It can be raised later on, the PVCs are expanded in place if their storage class allows volume expansion.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// Storage class of the PVCs, the default storage class is used if not specified.
+	// Only applies to PVCs created after it is set.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Access modes of the PVCs, ReadWriteOnce if not specified.
+	// Only applies to PVCs created after they are set.
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+
+	// Volume mode of the PVCs, only Filesystem is supported
+	// +optional
+	VolumeMode *corev1.PersistentVolumeMode `json:"volumeMode,omitempty"`
+
+	// Use an emptyDir instead of a PVC, for ephemeral test networks.
+	// The database is lost whenever a pod is deleted.
+	// +optional
+	EmptyDir bool `json:"emptyDir,omitempty"`
+
+	// Grow the PVCs as they fill up, based on the disk usage reported by the kubelet.
+	// Expansions require a storage class which allows volume expansion.
+	// +optional
+	AutoExpand *AutoExpandSpec `json:"autoExpand,omitempty"`
+}
+
+// AutoExpandSpec defines when and how much the PVCs are grown
+type AutoExpandSpec struct {
+	// Disk usage, in percent, above which a PVC is grown
+	// +optional
+	// +kubebuilder:default:=80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=99
+	ThresholdPercent int `json:"thresholdPercent,omitempty"`
+
+	// Size added to a PVC at every expansion, 10Gi if not specified
+	// +optional
+	Step *resource.Quantity `json:"step,omitempty"`
+
+	// PVCs are never grown past this size
+	MaxSize resource.Quantity `json:"maxSize"`
+}
+
+// TeardownSpec defines how the nodes are removed when the Avalanchego object is deleted
+type TeardownSpec struct {
+	// Take a VolumeSnapshot of the PVC of every node before deleting it.
+	// Snapshots are not deleted with the Avalanchego object.
+	// +optional
+	Snapshot bool `json:"snapshot,omitempty"`
+
+	// VolumeSnapshotClass of the snapshots, the default class is used if empty
+	// +optional
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+
+	// Keep the PVC of every node, released from the Avalanchego object, instead of deleting it
+	// +optional
+	ArchivePVCs bool `json:"archivePVCs,omitempty"`
+}
+
+// GenesisTemplate describes the genesis of a new network
+type GenesisTemplate struct {
+	// Funds of the network
+	// +optional
+	Allocations []GenesisAllocation `json:"allocations,omitempty"`
+
+	// X-Chain addresses of allocations whose locked funds are staked by the initial stakers
+	// +optional
+	InitialStakedFunds []string `json:"initialStakedFunds,omitempty"`
+
+	// Unix time the network starts at, it can't be in the future.
+	// Defaults to the creation of the network minus startTimeOffset
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	StartTime int64 `json:"startTime,omitempty"`
+
+	// Time the network starts at before its creation, when startTime is not given, 1h by default
+	// +optional
+	StartTimeOffset *metav1.Duration `json:"startTimeOffset,omitempty"`
+
+	// Seconds the initial stakers stake for
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	InitialStakeDuration int64 `json:"initialStakeDuration,omitempty"`
+
+	// Seconds between the end of the stakes of two initial stakers
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	InitialStakeDurationOffset int64 `json:"initialStakeDurationOffset,omitempty"`
+
+	// Reward address and delegation fee of the initial stakers: one entry per node, or a single entry for all of them
+	// +optional
+	Stakers []GenesisStaker `json:"stakers,omitempty"`
+
+	// Message of the genesis
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// GenesisAllocation funds an X-Chain address and a C-Chain address
+type GenesisAllocation struct {
+	// C-Chain address, e.g. 0xb3d82b1367d362de99ab59a658165aff520cbd4d
+	EthAddr string `json:"ethAddr"`
+
+	// X-Chain address, e.g. X-custom18jma8ppw3nhx5r4ap8clazz0dps7rv5u9xde7p
+	DjtxAddr string `json:"djtxAddr"`
+
+	// Amount available right away, in nDJTX
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	InitialAmount int64 `json:"initialAmount,omitempty"`
+
+	// Amounts locked until a given time
+	// +optional
+	UnlockSchedule []GenesisUnlock `json:"unlockSchedule,omitempty"`
+}
+
+// GenesisUnlock is an amount locked until a given time
+type GenesisUnlock struct {
+	// Amount in nDJTX
+	// +kubebuilder:validation:Minimum=1
+	Amount int64 `json:"amount"`
+
+	// Unix time the amount is unlocked at, unlocked right away if not given
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Locktime int64 `json:"locktime,omitempty"`
+}
+
+// GenesisStaker is how an initial staker is rewarded
+type GenesisStaker struct {
+	// X-Chain address receiving the staking rewards
+	// +optional
+	RewardAddress string `json:"rewardAddress,omitempty"`
+
+	// Fee charged to delegators, in ten thousandths of a percent: 20000 is 2%
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000000
+	DelegationFee *int `json:"delegationFee,omitempty"`
+}
+
+// CChainSpec configures the C-Chain. The fields other than config only apply to a generated genesis,
+// fields which are not given keep the values of the default C-Chain genesis.
+type CChainSpec struct {
+	// EVM chain ID
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ChainID int64 `json:"chainId,omitempty"`
+
+	// Gas limit of the genesis block
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	GasLimit int64 `json:"gasLimit,omitempty"`
+
+	// Prefunded accounts by 0x address, replacing the default one
+	// +optional
+	Alloc map[string]CChainAccount `json:"alloc,omitempty"`
+
+	// Dynamic fee config, for EVMs supporting it, e.g. subnet-evm. Its gas limit is gasLimit.
+	// +optional
+	FeeConfig *CChainFeeConfig `json:"feeConfig,omitempty"`
+
+	// Precompiles enabled from the genesis block, for EVMs supporting them, e.g. subnet-evm
+	// +optional
+	Precompiles CChainPrecompiles `json:"precompiles,omitempty"`
+
+	// Config of the C-Chain of every node, written to C/config.json in its chain config dir
+	// +optional
+	Config *CChainConfig `json:"config,omitempty"`
+}
+
+// CChainAccount is a prefunded EVM account
+type CChainAccount struct {
+	// Balance in wei, decimal or 0x hexadecimal
+	Balance string `json:"balance"`
+}
+
+// CChainFeeConfig sets the dynamic fees of the EVM
+type CChainFeeConfig struct {
+	// Target seconds between blocks
+	// +kubebuilder:validation:Minimum=1
+	TargetBlockRate int64 `json:"targetBlockRate"`
+
+	// Minimum base fee, in wei
+	// +kubebuilder:validation:Minimum=1
+	MinBaseFee int64 `json:"minBaseFee"`
+
+	// Gas targeted over 10 seconds
+	// +kubebuilder:validation:Minimum=1
+	TargetGas int64 `json:"targetGas"`
+
+	// Denominator of the change of the base fee between blocks
+	// +kubebuilder:validation:Minimum=1
+	BaseFeeChangeDenominator int64 `json:"baseFeeChangeDenominator"`
+
+	// Minimum block gas cost
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinBlockGasCost int64 `json:"minBlockGasCost,omitempty"`
+
+	// Maximum block gas cost, at least minBlockGasCost
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxBlockGasCost int64 `json:"maxBlockGasCost,omitempty"`
+
+	// Change of the block gas cost, for each second off targetBlockRate
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	BlockGasCostStep int64 `json:"blockGasCostStep,omitempty"`
+}
+
+// CChainPrecompiles enables precompiles, each one restricted to allow lists of 0x addresses
+type CChainPrecompiles struct {
+	// Restricts who can deploy contracts
+	// +optional
+	ContractDeployerAllowList *CChainAllowList `json:"contractDeployerAllowList,omitempty"`
+
+	// Restricts who can send transactions
+	// +optional
+	TxAllowList *CChainAllowList `json:"txAllowList,omitempty"`
+
+	// Allows minting native coins
+	// +optional
+	ContractNativeMinter *CChainAllowList `json:"contractNativeMinter,omitempty"`
+
+	// Allows changing the fee config
+	// +optional
+	FeeManager *CChainAllowList `json:"feeManager,omitempty"`
+}
+
+// CChainAllowList lists the addresses allowed to use a precompile
+type CChainAllowList struct {
+	// Addresses allowed to use the precompile, and to change the allow list
+	// +optional
+	AdminAddresses []string `json:"adminAddresses,omitempty"`
+
+	// Addresses allowed to use the precompile
+	// +optional
+	EnabledAddresses []string `json:"enabledAddresses,omitempty"`
+}
+
+// CChainConfig is the config of the C-Chain of a node
+type CChainConfig struct {
+	// Log level of the C-Chain
+	// +optional
+	// +kubebuilder:validation:Enum=trace;debug;info;warn;error;crit
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// Prunes the state of old blocks, on by default
+	// +optional
+	PruningEnabled *bool `json:"pruningEnabled,omitempty"`
+
+	// Treats the transactions submitted to the node as local ones
+	// +optional
+	LocalTxsEnabled *bool `json:"localTxsEnabled,omitempty"`
+
+	// Enables the snowman API
+	// +optional
+	SnowmanAPIEnabled *bool `json:"snowmanAPIEnabled,omitempty"`
+
+	// Gas cap of eth_call and eth_estimateGas
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	RPCGasCap int64 `json:"rpcGasCap,omitempty"`
 }
 
+// NetworkPreset names the network the nodes run
+// +kubebuilder:validation:Enum=mainnet;fuji;local;custom
+type NetworkPreset string
+
+const (
+	// MainnetNetwork is the main network, nodes bootstrap from its public beacons
+	MainnetNetwork NetworkPreset = "mainnet"
+	// FujiNetwork is the test network, nodes bootstrap from its public beacons
+	FujiNetwork NetworkPreset = "fuji"
+	// LocalNetwork uses the local genesis built into avalanchego
+	LocalNetwork NetworkPreset = "local"
+	// CustomNetwork uses its own genesis, generated by the operator unless spec.genesis is given
+	CustomNetwork NetworkPreset = "custom"
+)
+
+// StorageRetentionPolicy tells what to do with the storage of removed nodes
+// +kubebuilder:validation:Enum=Retain;Delete
+type StorageRetentionPolicy string
+
+const (
+	// RetainStorage keeps the PVC and the Secret of a removed node
+	RetainStorage StorageRetentionPolicy = "Retain"
+	// DeleteStorage deletes the PVC and the Secret of a removed node
+	DeleteStorage StorageRetentionPolicy = "Delete"
+)
+
 type Certificate struct {
 	Cert string `json:"cert"`
 	Key  string `json:"key"`
 }
 
+// NodeStatus is the observed state of a single node
+type NodeStatus struct {
+	// Index of the node, as used in the names of its objects
+	Index int `json:"index"`
+
+	// NodeID derived from the staking certificate of the node
+	// +optional
+	NodeID string `json:"nodeID,omitempty"`
+
+	// Role of the node, given by spec.bootstrappers
+	// +optional
+	Role NodeRole `json:"role,omitempty"`
+
+	// DNS name of the node service
+	ServiceName string `json:"serviceName"`
+
+	// IP address of the node pod
+	// +optional
+	PodIP string `json:"podIP,omitempty"`
+
+	// True if the StatefulSet of the node has a ready replica
+	Ready bool `json:"ready"`
+
+	// True if the node is one of the initial stakers of the genesis.
+	// Other nodes, e.g. the ones added by raising nodeCount, have to be added as validators on the P-Chain.
+	// Only known for genesis the operator has, generated or given in spec.genesis
+	// +optional
+	GenesisStaker bool `json:"genesisStaker,omitempty"`
+
+	// Time the stake of the node ends, for initial stakers of the genesis
+	// +optional
+	StakeEndTime *metav1.Time `json:"stakeEndTime,omitempty"`
+
+	// Result of the last /ext/health check
+	// +optional
+	Health *NodeHealth `json:"health,omitempty"`
+
+	// Outcome of seeding the database of the node from spec.dataSource
+	// +optional
+	Restore *RestoreStatus `json:"restore,omitempty"`
+}
+
+// NodeRole tells how a node joins the network
+type NodeRole string
+
+const (
+	// NodeRoleBootstrapper is a beacon of the other nodes, it bootstraps from the other bootstrappers only
+	NodeRoleBootstrapper NodeRole = "Bootstrapper"
+	// NodeRoleNode bootstraps from a quorum of the bootstrappers
+	NodeRoleNode NodeRole = "Node"
+)
+
+// RestorePhase is the state of the restore of a node database
+type RestorePhase string
+
+const (
+	// RestorePending means the volume is being provisioned, or the archive downloaded
+	RestorePending RestorePhase = "Pending"
+	// RestoreSucceeded means the database was restored
+	RestoreSucceeded RestorePhase = "Succeeded"
+	// RestoreSkipped means the archive wasn't unpacked, since the database already had data
+	RestoreSkipped RestorePhase = "Skipped"
+	// RestoreFailed means the archive couldn't be downloaded, verified or unpacked
+	RestoreFailed RestorePhase = "Failed"
+)
+
+// RestoreStatus is the outcome of seeding the database of a node
+type RestoreStatus struct {
+	// Where the database comes from: VolumeSnapshot/<name>, PersistentVolumeClaim/<name> or the URL of the archive
+	Source string `json:"source"`
+
+	Phase RestorePhase `json:"phase"`
+
+	// Details, e.g. why the restore failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// NodeHealth is the result of a health check of a node
+type NodeHealth struct {
+	Healthy bool `json:"healthy"`
+
+	// Failing checks, or the reason the node couldn't be checked
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Time of the check which changed healthy or message
+	LastChecked metav1.Time `json:"lastChecked"`
+}
+
+// AvalanchegoPhase is a short summary of the state of the network
+type AvalanchegoPhase string
+
+const (
+	// PhasePending means the operator has not created any node yet
+	PhasePending AvalanchegoPhase = "Pending"
+	// PhaseCreating means nodes are being created or updated and not all of them are ready
+	PhaseCreating AvalanchegoPhase = "Creating"
+	// PhaseRunning means all the nodes are ready
+	PhaseRunning AvalanchegoPhase = "Running"
+	// PhaseDegraded means the last reconciliation failed, see the Degraded condition
+	PhaseDegraded AvalanchegoPhase = "Degraded"
+	// PhaseTerminating means the object is deleted and the nodes are being removed, see status.teardown
+	PhaseTerminating AvalanchegoPhase = "Terminating"
+)
+
+// Condition types of Avalanchego
+const (
+	// ConditionReady is True when all the nodes are ready and the last reconciliation succeeded
+	ConditionReady = "Ready"
+	// ConditionBootstrapped is True when the bootstrapper of the network is available to the other nodes
+	ConditionBootstrapped = "Bootstrapped"
+	// ConditionSecretsReady is True when staking certificates and genesis are in place for every node
+	ConditionSecretsReady = "SecretsReady"
+	// ConditionProgressing is True while nodes are being created or updated
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded is True when the last reconciliation failed
+	ConditionDegraded = "Degraded"
+)
+
 // AvalanchegoStatus defines the observed state of Avalanchego
 type AvalanchegoStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -102,15 +764,203 @@ type AvalanchegoStatus struct {
 	// Node services list
 	NetworkMembersURI []string `json:"networkMembersURI"`
 
-	// genesis.json
-	Genesis string `json:"genesis"`
+	// genesis.json, only set for networks created before the genesis moved to genesisConfigMap
+	// +optional
+	Genesis string `json:"genesis,omitempty"`
+
+	// Immutable ConfigMap holding the genesis.json mounted into the nodes, named after its hash
+	// +optional
+	GenesisConfigMap string `json:"genesisConfigMap,omitempty"`
+
+	// SHA-256 of the genesis, hex encoded
+	// +optional
+	GenesisHash string `json:"genesisHash,omitempty"`
+
+	// Time the network starts at, from the genesis
+	// +optional
+	GenesisStartTime *metav1.Time `json:"genesisStartTime,omitempty"`
 
 	//String to indicate a logical error
 	Error string `json:"error,omitempty"`
+
+	// Short summary of the state of the network
+	// +optional
+	Phase AvalanchegoPhase `json:"phase,omitempty"`
+
+	// Generation of the spec the status was last computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Number of nodes with a ready pod
+	// +optional
+	ReadyNodes int `json:"readyNodes"`
+
+	// Number of nodes with a created StatefulSet
+	// +optional
+	CurrentNodes int `json:"currentNodes"`
+
+	// Observed state of every node
+	// +optional
+	Nodes []NodeStatus `json:"nodes,omitempty"`
+
+	// Standard conditions: Ready, Bootstrapped, SecretsReady, Progressing and Degraded
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Progress of the removal of the nodes, once the Avalanchego object is deleted
+	// +optional
+	Teardown *TeardownStatus `json:"teardown,omitempty"`
+
+	// PVCs grown by the operator as they filled up
+	// +optional
+	// +listType=map
+	// +listMapKey=pvc
+	VolumeExpansions []VolumeExpansion `json:"volumeExpansions,omitempty"`
+
+	// Snapshots taken by scheduled backups, oldest first
+	// +optional
+	Backups []Backup `json:"backups,omitempty"`
+
+	// Time the last backup was scheduled
+	// +optional
+	LastBackupTime *metav1.Time `json:"lastBackupTime,omitempty"`
+
+	// Progress of the last upgrade of the nodes to a new image
+	// +optional
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+}
+
+// UpgradePhase is the state of an upgrade
+type UpgradePhase string
+
+const (
+	// UpgradeProgressing means nodes are being upgraded
+	UpgradeProgressing UpgradePhase = "Progressing"
+	// UpgradeCompleted means all the nodes run the new image and are healthy
+	UpgradeCompleted UpgradePhase = "Completed"
+	// UpgradePaused means an upgraded node didn't become healthy, no more nodes are upgraded until the upgrade is resumed.
+	// The nodes which are not upgraded keep the previous image, other changes are still applied to them.
+	UpgradePaused UpgradePhase = "Paused"
+	// UpgradeRolledBack means an upgraded node didn't become healthy, the nodes run the previous image again
+	UpgradeRolledBack UpgradePhase = "RolledBack"
+)
+
+// ResumeUpgradeAnnotation resumes a paused upgrade when set on an instance, the operator removes it once read
+const ResumeUpgradeAnnotation = "chain.djtx.network/resume-upgrade"
+
+// UpgradeStatus is the progress of an upgrade of the nodes to a new image.
+// Another change of the image or tag starts a new upgrade.
+type UpgradeStatus struct {
+	// Image the nodes ran before the upgrade, e.g. avaplatform/avalanchego:v1.6.3
+	FromImage string `json:"fromImage"`
+
+	// Image the nodes are upgraded to
+	ToImage string `json:"toImage"`
+
+	Phase UpgradePhase `json:"phase"`
+
+	// Nodes upgraded so far, in the order they were upgraded
+	// +optional
+	Nodes []UpgradedNode `json:"nodes,omitempty"`
+
+	// Why the upgrade was paused or rolled back
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	StartTime metav1.Time `json:"startTime"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// UpgradedNode is a node upgraded to the new image
+type UpgradedNode struct {
+	// Index of the node
+	Index int `json:"index"`
+
+	// Time the StatefulSet of the node was upgraded
+	UpgradeTime metav1.Time `json:"upgradeTime"`
+
+	// Time the node was first found ready, healthy and bootstrapped after the upgrade
+	// +optional
+	HealthyTime *metav1.Time `json:"healthyTime,omitempty"`
+}
+
+// Backup is a VolumeSnapshot taken by a scheduled backup
+type Backup struct {
+	// Name of the VolumeSnapshot
+	Name string `json:"name"`
+
+	// Index of the node
+	Node int `json:"node"`
+
+	// Time the backup started
+	StartTime metav1.Time `json:"startTime"`
+
+	// Time the snapshot was taken, the node is restarted from then on
+	// +optional
+	SnapshotTime *metav1.Time `json:"snapshotTime,omitempty"`
+
+	// Minimum size of a volume restored from the snapshot
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// The snapshot can be used to restore a volume
+	// +optional
+	ReadyToUse bool `json:"readyToUse,omitempty"`
+
+	// Why the snapshot failed
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// VolumeExpansion records the automatic expansions of a PVC
+type VolumeExpansion struct {
+	// Name of the PVC
+	PVC string `json:"pvc"`
+
+	// Size requested by the last expansion
+	Size resource.Quantity `json:"size"`
+
+	// Disk usage, in percent, which triggered the last expansion
+	UsedPercent int `json:"usedPercent"`
+
+	// Number of expansions so far
+	Count int `json:"count"`
+
+	// Time of the last expansion
+	LastExpansionTime metav1.Time `json:"lastExpansionTime"`
+}
+
+// TeardownStatus is the progress of the removal of the nodes
+type TeardownStatus struct {
+	// Index of the node being removed, nodes are removed starting from the highest index
+	CurrentNode int `json:"currentNode"`
+
+	// What the removal of the current node is waiting for, or the last error
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// VolumeSnapshots taken so far
+	// +optional
+	Snapshots []string `json:"snapshots,omitempty"`
+
+	// Time the teardown started
+	StartTime metav1.Time `json:"startTime"`
+
+	// Last time the teardown made progress
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Ready Nodes",type=integer,JSONPath=`.status.readyNodes`
+//+kubebuilder:printcolumn:name="Nodes",type=integer,JSONPath=`.spec.nodeCount`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Avalanchego is the Schema for the avalanchegoes API
 type Avalanchego struct {
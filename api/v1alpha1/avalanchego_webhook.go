@@ -0,0 +1,948 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/lasthyphen/dijigo/ids"
+	avalanchegoConstants "github.com/lasthyphen/dijigo/utils/constants"
+	"github.com/lasthyphen/dijigo/utils/formatting"
+)
+
+// log is for logging in this package.
+var avalanchegolog = logf.Log.WithName("avalanchego-resource")
+
+const (
+	// DefaultNetworkID is the ID of custom networks, unless spec.networkID is given
+	DefaultNetworkID = 12346
+
+	// DefaultGenesisStartTimeOffset is the time a generated genesis starts at before the creation of the network,
+	// unless spec.genesisTemplate.startTime or startTimeOffset is given
+	DefaultGenesisStartTimeOffset = time.Hour
+
+	// DefaultGenesisInitialStakeDuration is the seconds the initial stakers stake for,
+	// unless spec.genesisTemplate.initialStakeDuration is given
+	DefaultGenesisInitialStakeDuration = 31536000
+
+	// DefaultGenesisInitialStakeDurationOffset is the seconds between the end of the stakes of two initial stakers,
+	// unless spec.genesisTemplate.initialStakeDurationOffset is given
+	DefaultGenesisInitialStakeDurationOffset = 5400
+
+	// DefaultGenesisStakedFunds is the allocation of the default genesis staked by the initial stakers,
+	// unless spec.genesisTemplate.initialStakedFunds is given
+	DefaultGenesisStakedFunds = "X-custom1g65uqn6t77p656w64023nh8nd9updzmxwd59gh"
+
+	// RemovedEnvAnnotation lists reserved environment variables the defaulting webhook removed from spec.env
+	RemovedEnvAnnotation = "chain.djtx.network/removed-env"
+
+	// DefaultStorageSize is the size of the PVC of a node, unless spec.storage.size is given
+	DefaultStorageSize = "50Gi"
+
+	// DefaultAutoExpandStep is the size added to a PVC by an automatic expansion, unless spec.storage.autoExpand.step is given
+	DefaultAutoExpandStep = "10Gi"
+
+	// DefaultAutoExpandThreshold is the disk usage triggering an automatic expansion, unless spec.storage.autoExpand.thresholdPercent is given
+	DefaultAutoExpandThreshold = 80
+
+	// DefaultBackupKeep is the number of backup snapshots kept, unless spec.backup.keep is given
+	DefaultBackupKeep = 3
+
+	// DefaultProgressDeadlineSeconds is the time an upgraded node has to become healthy, unless spec.upgradeStrategy.progressDeadlineSeconds is given
+	DefaultProgressDeadlineSeconds = 1800
+
+	// DefaultArchiveImage is the image downloading database archives, unless spec.dataSource.archive.image is given
+	DefaultArchiveImage = "curlimages/curl:7.79.1"
+)
+
+// ReservedEnvVars are environment variables set by the operator itself, they can't be overridden with spec.env
+var ReservedEnvVars = []string{
+	"AVAGO_PUBLIC_IP",
+	"AVAGO_HTTP_HOST",
+	"AVAGO_STAKING_TLS_CERT_FILE",
+	"AVAGO_STAKING_TLS_KEY_FILE",
+	"AVAGO_DB_DIR",
+	"AVAGO_HTTP_PORT",
+	"AVAGO_STAKING_PORT",
+	"AVAGO_GENESIS",
+	"AVAGO_NETWORK_ID",
+}
+
+// networkPresetIDs are the IDs of the well-known networks
+var networkPresetIDs = map[NetworkPreset]uint32{
+	MainnetNetwork: avalanchegoConstants.MainnetID,
+	FujiNetwork:    avalanchegoConstants.FujiID,
+	LocalNetwork:   avalanchegoConstants.LocalID,
+}
+
+func (r *Avalanchego) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-chain-djtx-network-v1alpha1-avalanchego,mutating=true,failurePolicy=fail,sideEffects=None,groups=chain.djtx.network,resources=avalanchegoes,verbs=create;update,versions=v1alpha1,name=mavalanchego.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Defaulter = &Avalanchego{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Avalanchego) Default() {
+	avalanchegolog.Info("default", "name", r.Name)
+
+	r.Spec.setDefaults()
+
+	env, removed := FilterReservedEnv(r.Spec.Env)
+	if len(removed) > 0 {
+		if r.Annotations == nil {
+			r.Annotations = map[string]string{}
+		}
+		r.Annotations[RemovedEnvAnnotation] = strings.Join(removed, ",") + ": reserved, these variables are set by the operator"
+	} else {
+		// Nothing was removed from this version of the spec, a previous note no longer applies
+		delete(r.Annotations, RemovedEnvAnnotation)
+	}
+	r.Spec.Env = env
+}
+
+// WithDefaults returns a copy of the spec with the defaults of the defaulting webhook filled in.
+// The controller reads defaulted fields through it, since the operator may be deployed without webhooks.
+func (s *AvalanchegoSpec) WithDefaults() AvalanchegoSpec {
+	d := s.DeepCopy()
+	d.setDefaults()
+	return *d
+}
+
+func (s *AvalanchegoSpec) setDefaults() {
+	if s.NodeCount == 0 {
+		s.NodeCount = 5
+	}
+	if s.DeploymentName == "" {
+		s.DeploymentName = "test-validator"
+	}
+	if s.Image == "" {
+		s.Image = "avaplatform/avalanchego"
+	}
+	if s.Tag == "" {
+		s.Tag = "latest"
+	}
+	if s.StorageRetentionPolicy == "" {
+		s.StorageRetentionPolicy = RetainStorage
+	}
+	if s.Storage.Size == nil && !s.Storage.EmptyDir {
+		size := resource.MustParse(DefaultStorageSize)
+		s.Storage.Size = &size
+	}
+	if s.Backup != nil && s.Backup.Keep == 0 {
+		s.Backup.Keep = DefaultBackupKeep
+	}
+	if s.UpgradeStrategy.Type == "" {
+		s.UpgradeStrategy.Type = RollingUpdateStrategy
+	}
+	if s.UpgradeStrategy.MaxUnavailable == 0 {
+		s.UpgradeStrategy.MaxUnavailable = 1
+	}
+	if s.UpgradeStrategy.ProgressDeadlineSeconds == 0 {
+		s.UpgradeStrategy.ProgressDeadlineSeconds = DefaultProgressDeadlineSeconds
+	}
+	if s.UpgradeStrategy.FailurePolicy == "" {
+		s.UpgradeStrategy.FailurePolicy = PauseOnFailure
+	}
+	if s.UpgradeStrategy.Type == CanaryStrategy && s.UpgradeStrategy.Canary == nil {
+		s.UpgradeStrategy.Canary = &CanarySpec{}
+	}
+	if canary := s.UpgradeStrategy.Canary; canary != nil && canary.Nodes == 0 {
+		canary.Nodes = 1
+	}
+	if s.DataSource != nil && s.DataSource.Archive != nil && s.DataSource.Archive.Image == "" {
+		s.DataSource.Archive.Image = DefaultArchiveImage
+	}
+	if autoExpand := s.Storage.AutoExpand; autoExpand != nil {
+		if autoExpand.ThresholdPercent == 0 {
+			autoExpand.ThresholdPercent = DefaultAutoExpandThreshold
+		}
+		if autoExpand.Step == nil {
+			step := resource.MustParse(DefaultAutoExpandStep)
+			autoExpand.Step = &step
+		}
+	}
+	if len(s.Storage.AccessModes) == 0 && !s.Storage.EmptyDir {
+		s.Storage.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+
+	// Make the network explicit, so the stored object shows what the nodes run with
+	s.DefaultNetwork()
+}
+
+// DefaultNetwork fills in network and networkID.
+// A network ID given with AVAGO_NETWORK_ID in env, before networkID existed, is moved into networkID:
+// the variable itself is reserved.
+func (s *AvalanchegoSpec) DefaultNetwork() {
+	if s.NetworkID == 0 && s.Network == "" {
+		if networkID, ok := s.envNetworkID(); ok {
+			s.NetworkID = networkID
+		}
+	}
+	s.NetworkID = s.GetNetworkID()
+	if s.Network == "" {
+		s.Network = networkPresetOf(s.NetworkID)
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-chain-djtx-network-v1alpha1-avalanchego,mutating=false,failurePolicy=fail,sideEffects=None,groups=chain.djtx.network,resources=avalanchegoes,verbs=create;update,versions=v1alpha1,name=vavalanchego.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &Avalanchego{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Avalanchego) ValidateCreate() error {
+	avalanchegolog.Info("validate create", "name", r.Name)
+
+	allErrs := r.validateSpec()
+	// Checked against the current time and nodeCount, so only on creation: an existing network keeps running past them,
+	// and nodes added later are not initial stakers
+	if r.Spec.GenesisTemplate != nil {
+		allErrs = append(allErrs, r.validateGenesisStakes(field.NewPath("spec", "genesisTemplate"))...)
+	}
+	return r.toAPIError(allErrs)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Avalanchego) ValidateUpdate(old runtime.Object) error {
+	avalanchegolog.Info("validate update", "name", r.Name)
+
+	oldInstance, ok := old.(*Avalanchego)
+	if !ok {
+		return apierrors.NewBadRequest("expected an Avalanchego object")
+	}
+
+	allErrs := r.validateSpec()
+	specPath := field.NewPath("spec")
+
+	if r.Spec.DeploymentName != oldInstance.Spec.DeploymentName {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("deploymentName"), "field is immutable"))
+	}
+	// Nodes keep their database, so they can't move to another network
+	if r.Spec.GetNetworkID() != oldInstance.Spec.GetNetworkID() {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("networkID"), "field is immutable"))
+	}
+	// The genesis is generated once, before the nodes are created
+	if oldInstance.Spec.GenesisTemplate != nil && !equality.Semantic.DeepEqual(r.Spec.GenesisTemplate, oldInstance.Spec.GenesisTemplate) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("genesisTemplate"), "field is immutable once set"))
+	}
+	if oldGenesis := oldInstance.Spec.CChain.genesis(); oldGenesis != nil && !equality.Semantic.DeepEqual(r.Spec.CChain.genesis(), oldGenesis) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("cChain"), "only config can be changed once the genesis is set"))
+	}
+	// Nodes keep their database, so the genesis of a running network cannot be swapped
+	if oldInstance.Spec.Genesis != "" && r.Spec.Genesis != oldInstance.Spec.Genesis {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("genesis"), "field is immutable once set"))
+	}
+	// The database of running nodes would be swapped between a PVC and an emptyDir
+	if r.Spec.Storage.EmptyDir != oldInstance.Spec.Storage.EmptyDir {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("storage", "emptyDir"), "field is immutable"))
+	}
+	// PVCs can be expanded, but never shrunk
+	if r.Spec.Storage.Size != nil && oldInstance.Spec.Storage.Size != nil && r.Spec.Storage.Size.Cmp(*oldInstance.Spec.Storage.Size) < 0 {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("storage", "size"), "can't be lowered, PVCs can't shrink"))
+	}
+
+	return r.toAPIError(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Avalanchego) ValidateDelete() error {
+	return nil
+}
+
+// ValidateSpec checks the spec for combinations of fields the operator cannot reconcile.
+// It is called by the validating webhook and, as a fallback, by the controller.
+func (r *Avalanchego) ValidateSpec() error {
+	return r.toAPIError(r.validateSpec())
+}
+
+func (r *Avalanchego) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	// Nodes are removed starting from the highest index, the first node always stays
+	if r.Spec.NodeCount < 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("nodeCount"), r.Spec.NodeCount, "must be at least 1, the first node (the bootstrapper of generated networks) can't be removed"))
+	}
+	if r.Spec.MinValidators < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("minValidators"), r.Spec.MinValidators, "must not be negative"))
+	} else if r.Spec.NodeCount < r.Spec.MinValidators {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("nodeCount"), r.Spec.NodeCount, "must not be lower than minValidators ("+strconv.Itoa(r.Spec.MinValidators)+")"))
+	}
+	if r.Spec.Teardown.VolumeSnapshotClassName != "" && !r.Spec.Teardown.Snapshot {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("teardown", "volumeSnapshotClassName"), r.Spec.Teardown.VolumeSnapshotClassName, "requires snapshot to be enabled"))
+	}
+	if r.Spec.AdoptSecrets && len(r.Spec.ExistingSecrets) == 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("adoptSecrets"), r.Spec.AdoptSecrets, "requires existingSecrets"))
+	}
+	allErrs = append(allErrs, r.Spec.Storage.validate(specPath.Child("storage"))...)
+	if r.Spec.Backup != nil {
+		allErrs = append(allErrs, r.validateBackup(specPath.Child("backup"))...)
+	}
+	if r.Spec.DataSource != nil {
+		allErrs = append(allErrs, r.validateDataSource(specPath.Child("dataSource"))...)
+	}
+	allErrs = append(allErrs, r.Spec.UpgradeStrategy.validate(specPath.Child("upgradeStrategy"))...)
+	allErrs = append(allErrs, r.validateBootstrappers(specPath.Child("bootstrappers"))...)
+	probesPath := specPath.Child("probes")
+	allErrs = append(allErrs, r.Spec.Probes.Startup.validate(probesPath.Child("startup"))...)
+	allErrs = append(allErrs, r.Spec.Probes.Readiness.validate(probesPath.Child("readiness"))...)
+	allErrs = append(allErrs, r.Spec.Probes.Liveness.validate(probesPath.Child("liveness"))...)
+	switch r.Spec.StorageRetentionPolicy {
+	case "", RetainStorage, DeleteStorage:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("storageRetentionPolicy"), r.Spec.StorageRetentionPolicy, []string{string(RetainStorage), string(DeleteStorage)}))
+	}
+
+	// Number of certs should match nodeCount
+	if len(r.Spec.Certificates) > 0 && len(r.Spec.Certificates) != r.Spec.NodeCount {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("certificates"), len(r.Spec.Certificates), "Number of provided certificate does not match nodeCount"))
+	}
+	// Number of secrets should match nodeCount
+	if len(r.Spec.ExistingSecrets) > 0 && len(r.Spec.ExistingSecrets) != r.Spec.NodeCount {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("existingSecrets"), len(r.Spec.ExistingSecrets), "Number of provided secrets does not match nodeCount"))
+	}
+	// Genesis should be inside secrets or omitted for mainnet, fuji or local networks
+	if len(r.Spec.ExistingSecrets) > 0 && r.Spec.Genesis != "" {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("genesis"), "Genesis cannot be specified when using pre-defined secrets. genesis.json key should be avaliable in secret instead and AVAGO_GENESIS env var provided."))
+	}
+	// Certificates should be inside secrets
+	if len(r.Spec.ExistingSecrets) > 0 && len(r.Spec.Certificates) > 0 {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("certificates"), "Certificates cannot be specified when using pre-defined secrets."))
+	}
+
+	for i, c := range r.Spec.Certificates {
+		certPath := specPath.Child("certificates").Index(i)
+		if _, err := base64.StdEncoding.DecodeString(c.Cert); err != nil {
+			allErrs = append(allErrs, field.Invalid(certPath.Child("cert"), "<redacted>", "must be base64 encoded: "+err.Error()))
+		}
+		if _, err := base64.StdEncoding.DecodeString(c.Key); err != nil {
+			allErrs = append(allErrs, field.Invalid(certPath.Child("key"), "<redacted>", "must be base64 encoded: "+err.Error()))
+		}
+	}
+
+	allErrs = append(allErrs, r.validateNetwork(specPath)...)
+	if r.Spec.GenesisTemplate != nil {
+		allErrs = append(allErrs, r.validateGenesisTemplate(specPath.Child("genesisTemplate"))...)
+	}
+	if r.Spec.CChain != nil {
+		allErrs = append(allErrs, r.validateCChain(specPath.Child("cChain"))...)
+	}
+
+	// Nodes of a custom network can't attach to it without knowing its genesis
+	if r.Spec.AttachesToNetwork() && r.Spec.IsCustomNetwork() && r.Spec.Genesis == "" && len(r.Spec.ExistingSecrets) == 0 {
+		allErrs = append(allErrs, field.Required(specPath.Child("genesis"), "genesis is required to attach to an existing custom network (network ID "+strconv.FormatUint(uint64(r.Spec.GetNetworkID()), 10)+")"))
+	}
+
+	return allErrs
+}
+
+func (s StorageSpec) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.Size != nil && s.Size.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("size"), s.Size.String(), "must be greater than zero"))
+	}
+	// The database is kept in a directory, a raw block device can't be mounted there
+	if s.VolumeMode != nil && *s.VolumeMode != corev1.PersistentVolumeFilesystem {
+		allErrs = append(allErrs, field.NotSupported(path.Child("volumeMode"), *s.VolumeMode, []string{string(corev1.PersistentVolumeFilesystem)}))
+	}
+	// No PVC is created for an emptyDir
+	if s.EmptyDir {
+		if s.StorageClassName != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("storageClassName"), "can't be combined with emptyDir"))
+		}
+		if len(s.AccessModes) > 0 {
+			allErrs = append(allErrs, field.Forbidden(path.Child("accessModes"), "can't be combined with emptyDir"))
+		}
+		if s.VolumeMode != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("volumeMode"), "can't be combined with emptyDir"))
+		}
+		if s.AutoExpand != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("autoExpand"), "can't be combined with emptyDir"))
+		}
+	}
+	if autoExpand := s.AutoExpand; autoExpand != nil {
+		autoExpandPath := path.Child("autoExpand")
+		if autoExpand.ThresholdPercent < 0 || autoExpand.ThresholdPercent > 99 {
+			allErrs = append(allErrs, field.Invalid(autoExpandPath.Child("thresholdPercent"), autoExpand.ThresholdPercent, "must be between 1 and 99"))
+		}
+		if autoExpand.Step != nil && autoExpand.Step.Sign() <= 0 {
+			allErrs = append(allErrs, field.Invalid(autoExpandPath.Child("step"), autoExpand.Step.String(), "must be greater than zero"))
+		}
+		if autoExpand.MaxSize.Sign() <= 0 {
+			allErrs = append(allErrs, field.Required(autoExpandPath.Child("maxSize"), "must be greater than zero"))
+		} else if s.Size != nil && autoExpand.MaxSize.Cmp(*s.Size) < 0 {
+			allErrs = append(allErrs, field.Invalid(autoExpandPath.Child("maxSize"), autoExpand.MaxSize.String(), "must not be lower than size ("+s.Size.String()+")"))
+		}
+	}
+	return allErrs
+}
+
+func (r *Avalanchego) validateBackup(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	backup := r.Spec.Backup
+	if schedule, err := ParseCronSchedule(backup.Schedule); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("schedule"), backup.Schedule, err.Error()))
+	} else if schedule.Next(time.Now().UTC()).IsZero() {
+		allErrs = append(allErrs, field.Invalid(path.Child("schedule"), backup.Schedule, "never matches"))
+	}
+	if backup.Node < 0 || backup.Node >= r.Spec.NodeCount {
+		allErrs = append(allErrs, field.Invalid(path.Child("node"), backup.Node, "must be the index of a node, lower than nodeCount"))
+	}
+	if backup.Keep < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("keep"), backup.Keep, "must not be negative"))
+	}
+	if r.Spec.Storage.EmptyDir {
+		allErrs = append(allErrs, field.Forbidden(path, "can't be combined with storage.emptyDir, there is no PVC to snapshot"))
+	}
+	return allErrs
+}
+
+func (p ProbeSpec) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	thresholds := []struct {
+		name  string
+		value int32
+	}{
+		{"initialDelaySeconds", p.InitialDelaySeconds},
+		{"periodSeconds", p.PeriodSeconds},
+		{"timeoutSeconds", p.TimeoutSeconds},
+		{"failureThreshold", p.FailureThreshold},
+	}
+	for _, t := range thresholds {
+		if t.value < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child(t.name), t.value, "must not be negative"))
+		}
+	}
+	return allErrs
+}
+
+func (u UpgradeStrategy) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	switch u.Type {
+	case "", RollingUpdateStrategy:
+		if u.Canary != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("canary"), "requires type "+string(CanaryStrategy)))
+		}
+	case CanaryStrategy:
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("type"), u.Type, []string{string(RollingUpdateStrategy), string(CanaryStrategy)}))
+	}
+	switch u.FailurePolicy {
+	case "", PauseOnFailure, RollbackOnFailure:
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("failurePolicy"), u.FailurePolicy, []string{string(PauseOnFailure), string(RollbackOnFailure)}))
+	}
+	if u.MaxUnavailable < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxUnavailable"), u.MaxUnavailable, "must be at least 1"))
+	}
+	if u.ProgressDeadlineSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("progressDeadlineSeconds"), u.ProgressDeadlineSeconds, "must be at least 1"))
+	}
+	if canary := u.Canary; canary != nil {
+		if canary.Nodes < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("canary", "nodes"), canary.Nodes, "must be at least 1"))
+		}
+		if canary.SoakSeconds < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("canary", "soakSeconds"), canary.SoakSeconds, "must not be negative"))
+		}
+	}
+	return allErrs
+}
+
+func (r *Avalanchego) validateBootstrappers(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if len(r.Spec.Bootstrappers) > 0 && r.Spec.BootstrapperURL != "" {
+		allErrs = append(allErrs, field.Forbidden(path, "can't be combined with bootstrapperURL"))
+	}
+	nodes := map[int]bool{}
+	addresses := map[string]bool{}
+	for i, b := range r.Spec.Bootstrappers {
+		bootstrapperPath := path.Index(i)
+		switch {
+		case b.Node != nil && b.Address != "":
+			allErrs = append(allErrs, field.Forbidden(bootstrapperPath, "only one of node and address can be given"))
+		case b.Node != nil:
+			if *b.Node < 0 || *b.Node >= r.Spec.NodeCount {
+				allErrs = append(allErrs, field.Invalid(bootstrapperPath.Child("node"), *b.Node, "must be the index of a node, lower than nodeCount"))
+			} else if nodes[*b.Node] {
+				allErrs = append(allErrs, field.Duplicate(bootstrapperPath.Child("node"), *b.Node))
+			}
+			nodes[*b.Node] = true
+			if b.NodeID != "" {
+				allErrs = append(allErrs, field.Forbidden(bootstrapperPath.Child("nodeID"), "the NodeID of a node of this deployment is known by the operator"))
+			}
+		case b.Address != "":
+			host, port, err := net.SplitHostPort(b.Address)
+			if err != nil || host == "" {
+				allErrs = append(allErrs, field.Invalid(bootstrapperPath.Child("address"), b.Address, "must be host:port"))
+			} else if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+				allErrs = append(allErrs, field.Invalid(bootstrapperPath.Child("address"), b.Address, "must have a valid port"))
+			} else if addresses[b.Address] {
+				allErrs = append(allErrs, field.Duplicate(bootstrapperPath.Child("address"), b.Address))
+			}
+			addresses[b.Address] = true
+			if b.NodeID == "" {
+				allErrs = append(allErrs, field.Required(bootstrapperPath.Child("nodeID"), "is required for an external node"))
+			} else if _, err := ids.ShortFromPrefixedString(b.NodeID, avalanchegoConstants.NodeIDPrefix); err != nil {
+				allErrs = append(allErrs, field.Invalid(bootstrapperPath.Child("nodeID"), b.NodeID, "must be a NodeID: "+err.Error()))
+			}
+		default:
+			allErrs = append(allErrs, field.Required(bootstrapperPath, "one of node and address is required"))
+		}
+	}
+	return allErrs
+}
+
+func (r *Avalanchego) validateDataSource(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	source := r.Spec.DataSource
+	sources := 0
+	if source.VolumeSnapshot != "" {
+		sources++
+	}
+	if source.PersistentVolumeClaim != "" {
+		sources++
+	}
+	if source.Archive != nil {
+		sources++
+	}
+	if sources != 1 {
+		allErrs = append(allErrs, field.Invalid(path, sources, "exactly one of volumeSnapshot, persistentVolumeClaim and archive must be given"))
+	}
+	if r.Spec.Storage.EmptyDir && (source.VolumeSnapshot != "" || source.PersistentVolumeClaim != "") {
+		allErrs = append(allErrs, field.Forbidden(path, "volumeSnapshot and persistentVolumeClaim can't be combined with storage.emptyDir, only archive can"))
+	}
+	if archive := source.Archive; archive != nil {
+		archivePath := path.Child("archive")
+		if u, err := url.Parse(archive.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(archivePath.Child("url"), archive.URL, "must be an http or https URL"))
+		}
+		if sum, err := hex.DecodeString(archive.SHA256); err != nil || len(sum) != sha256.Size {
+			allErrs = append(allErrs, field.Invalid(archivePath.Child("sha256"), archive.SHA256, "must be a hex encoded SHA-256 checksum"))
+		}
+	}
+	return allErrs
+}
+
+func (r *Avalanchego) toAPIError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Avalanchego").GroupKind(), r.Name, allErrs)
+}
+
+func (r *Avalanchego) validateNetwork(specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	networkID := r.Spec.GetNetworkID()
+	switch r.Spec.Network {
+	case "":
+	case CustomNetwork:
+		if !isCustomNetworkID(networkID) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("networkID"), networkID, "is the ID of the "+string(networkPresetOf(networkID))+" network, set network instead"))
+		}
+	default:
+		if r.Spec.NetworkID != 0 && r.Spec.NetworkID != networkPresetIDs[r.Spec.Network] {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("networkID"), r.Spec.NetworkID, "must be "+strconv.FormatUint(uint64(networkPresetIDs[r.Spec.Network]), 10)+" for the "+string(r.Spec.Network)+" network"))
+		}
+	}
+
+	// Well-known networks have their genesis built into avalanchego
+	if !r.Spec.IsCustomNetwork() && r.Spec.Genesis != "" {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("genesis"), "only custom networks have a genesis"))
+	}
+	if r.Spec.Genesis != "" {
+		var g struct {
+			NetworkID int `json:"networkID"`
+		}
+		if err := json.Unmarshal([]byte(r.Spec.Genesis), &g); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("genesis"), "<genesis>", "must be valid JSON: "+err.Error()))
+		} else if g.NetworkID != 0 && uint32(g.NetworkID) != networkID {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("genesis"), "<genesis>", "networkID of the genesis is "+strconv.Itoa(g.NetworkID)+", not "+strconv.FormatUint(uint64(networkID), 10)))
+		}
+	}
+	return allErrs
+}
+
+// validateGeneratesGenesis returns an error if the genesis of the network isn't generated by the operator,
+// path being a field which only applies to a generated genesis
+func (r *Avalanchego) validateGeneratesGenesis(path *field.Path) field.ErrorList {
+	switch {
+	case r.Spec.Genesis != "":
+		return field.ErrorList{field.Forbidden(path, "can't be combined with genesis")}
+	case len(r.Spec.Certificates) > 0 || len(r.Spec.ExistingSecrets) > 0:
+		return field.ErrorList{field.Forbidden(path, "can't be combined with certificates or existingSecrets, the keys of the initial stakers are generated")}
+	case r.Spec.AttachesToNetwork():
+		return field.ErrorList{field.Forbidden(path, "only applies to new networks")}
+	case !r.Spec.IsCustomNetwork():
+		return field.ErrorList{field.Forbidden(path, "only custom networks have a genesis")}
+	}
+	return nil
+}
+
+func (r *Avalanchego) validateGenesisTemplate(path *field.Path) field.ErrorList {
+	allErrs := r.validateGeneratesGenesis(path)
+	t := r.Spec.GenesisTemplate
+	hrp := avalanchegoConstants.GetHRP(r.Spec.GetNetworkID())
+
+	allocated := map[string]bool{}
+	for i, a := range t.Allocations {
+		allocationPath := path.Child("allocations").Index(i)
+		if !isEthAddress(a.EthAddr) {
+			allErrs = append(allErrs, field.Invalid(allocationPath.Child("ethAddr"), a.EthAddr, "must be 0x followed by 40 hexadecimal digits"))
+		}
+		if err := validateXChainAddress(a.DjtxAddr, hrp); err != nil {
+			allErrs = append(allErrs, field.Invalid(allocationPath.Child("djtxAddr"), a.DjtxAddr, err.Error()))
+		}
+		allocated[a.DjtxAddr] = true
+	}
+	for i, address := range t.InitialStakedFunds {
+		fundsPath := path.Child("initialStakedFunds").Index(i)
+		if err := validateXChainAddress(address, hrp); err != nil {
+			allErrs = append(allErrs, field.Invalid(fundsPath, address, err.Error()))
+		}
+		for _, previous := range t.InitialStakedFunds[:i] {
+			if previous == address {
+				allErrs = append(allErrs, field.Duplicate(fundsPath, address))
+			}
+		}
+	}
+	for i, staker := range t.Stakers {
+		if staker.RewardAddress == "" {
+			continue
+		}
+		if err := validateXChainAddress(staker.RewardAddress, hrp); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("stakers").Index(i).Child("rewardAddress"), staker.RewardAddress, err.Error()))
+		}
+	}
+	if t.StartTimeOffset != nil {
+		if t.StartTime > 0 {
+			allErrs = append(allErrs, field.Forbidden(path.Child("startTimeOffset"), "can't be combined with startTime"))
+		} else if t.StartTimeOffset.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("startTimeOffset"), t.StartTimeOffset.Duration.String(), "can't be negative, the network can't start in the future"))
+		}
+	}
+
+	// The fields which are not given come from the default genesis, whose allocations are valid
+	if len(t.Allocations) > 0 {
+		for i, address := range t.GetInitialStakedFunds() {
+			if allocated[address] {
+				continue
+			}
+			if len(t.InitialStakedFunds) == 0 {
+				allErrs = append(allErrs, field.Required(path.Child("initialStakedFunds"), "the default "+address+" is not the djtxAddr of an allocation"))
+			} else {
+				allErrs = append(allErrs, field.Invalid(path.Child("initialStakedFunds").Index(i), address, "must be the djtxAddr of an allocation"))
+			}
+		}
+		var supply int64
+		for _, a := range t.Allocations {
+			supply += a.InitialAmount
+			for _, unlock := range a.UnlockSchedule {
+				supply += unlock.Amount
+			}
+		}
+		if supply <= 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("allocations"), supply, "the initial supply must be greater than zero"))
+		}
+	}
+	if duration := t.GetInitialStakeDuration(); duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("initialStakeDuration"), duration, "must be greater than zero"))
+	}
+	return allErrs
+}
+
+// validateGenesisStakes returns an error if the generated genesis would start in the future,
+// if initialStakeDuration is too short for the offsets between the initial stakers,
+// or if their stakes would be over by the time the network is created
+func (r *Avalanchego) validateGenesisStakes(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	t := r.Spec.GenesisTemplate
+	now := time.Now()
+	if t.StartTime > 0 && time.Unix(t.StartTime, 0).After(now) {
+		allErrs = append(allErrs, field.Invalid(path.Child("startTime"), t.StartTime, "can't be in the future"))
+	}
+	duration, offset := t.GetInitialStakeDuration(), t.GetInitialStakeDurationOffset()
+	// The stake of the last initial staker ends first
+	required := offset * int64(r.Spec.NodeCount-1)
+	if required > duration {
+		allErrs = append(allErrs, field.Invalid(path.Child("initialStakeDuration"), duration, "must be at least "+strconv.FormatInt(required, 10)+" with an offset of "+strconv.FormatInt(offset, 10)+" between "+strconv.Itoa(r.Spec.NodeCount)+" stakers"))
+	} else if end := time.Unix(t.GetStartTime(now)+duration-required, 0); !end.After(now) {
+		allErrs = append(allErrs, field.Invalid(path.Child("initialStakeDuration"), duration, "the stakes of the initial stakers would be over by "+end.UTC().Format(time.RFC3339)))
+	}
+	return allErrs
+}
+
+func (r *Avalanchego) validateCChain(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	c := r.Spec.CChain
+	if c.ChangesGenesis() {
+		allErrs = append(allErrs, r.validateGeneratesGenesis(path)...)
+	}
+	for address, account := range c.Alloc {
+		accountPath := path.Child("alloc").Key(address)
+		if !isEthAddress(address) {
+			allErrs = append(allErrs, field.Invalid(accountPath, address, "must be 0x followed by 40 hexadecimal digits"))
+		}
+		if balance, ok := new(big.Int).SetString(account.Balance, 0); !ok || balance.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(accountPath.Child("balance"), account.Balance, "must be a positive amount of wei, decimal or 0x hexadecimal"))
+		}
+	}
+	// The operator mounts the config into the chain config dir it sets
+	if c.Config != nil && indexOfEnv(r.Spec.Env, "AVAGO_CHAIN_CONFIG_DIR") != -1 {
+		allErrs = append(allErrs, field.Forbidden(path.Child("config"), "can't be combined with AVAGO_CHAIN_CONFIG_DIR in env, the operator sets the chain config dir"))
+	}
+	if f := c.FeeConfig; f != nil && f.MaxBlockGasCost < f.MinBlockGasCost {
+		allErrs = append(allErrs, field.Invalid(path.Child("feeConfig", "maxBlockGasCost"), f.MaxBlockGasCost, "must be at least minBlockGasCost"))
+	}
+	precompilesPath := path.Child("precompiles")
+	for name, allowList := range map[string]*CChainAllowList{
+		"contractDeployerAllowList": c.Precompiles.ContractDeployerAllowList,
+		"txAllowList":               c.Precompiles.TxAllowList,
+		"contractNativeMinter":      c.Precompiles.ContractNativeMinter,
+		"feeManager":                c.Precompiles.FeeManager,
+	} {
+		if allowList == nil {
+			continue
+		}
+		for i, address := range allowList.AdminAddresses {
+			if !isEthAddress(address) {
+				allErrs = append(allErrs, field.Invalid(precompilesPath.Child(name, "adminAddresses").Index(i), address, "must be 0x followed by 40 hexadecimal digits"))
+			}
+		}
+		for i, address := range allowList.EnabledAddresses {
+			if !isEthAddress(address) {
+				allErrs = append(allErrs, field.Invalid(precompilesPath.Child(name, "enabledAddresses").Index(i), address, "must be 0x followed by 40 hexadecimal digits"))
+			}
+		}
+	}
+	return allErrs
+}
+
+// ChangesGenesis returns true if the spec has fields rendered into the C-Chain genesis, besides config
+func (c *CChainSpec) ChangesGenesis() bool {
+	return c.genesis() != nil
+}
+
+// genesis returns the part of the spec rendered into the C-Chain genesis, nil if nothing is given
+func (c *CChainSpec) genesis() *CChainSpec {
+	if c == nil {
+		return nil
+	}
+	g := c.DeepCopy()
+	g.Config = nil
+	if equality.Semantic.DeepEqual(g, &CChainSpec{}) {
+		return nil
+	}
+	return g
+}
+
+// GetNetworkID returns the ID of the network the nodes run: networkID, or the ID of network if not given
+func (s *AvalanchegoSpec) GetNetworkID() uint32 {
+	if s.NetworkID != 0 {
+		return s.NetworkID
+	}
+	if networkID, ok := networkPresetIDs[s.Network]; ok {
+		return networkID
+	}
+	// The defaulting webhook moves it into networkID, but it may not be deployed
+	if networkID, ok := s.envNetworkID(); ok && s.Network == "" {
+		return networkID
+	}
+	return DefaultNetworkID
+}
+
+// GetInitialStakedFunds returns initialStakedFunds, or the default staked funds if not given
+func (t *GenesisTemplate) GetInitialStakedFunds() []string {
+	if t == nil || len(t.InitialStakedFunds) == 0 {
+		return []string{DefaultGenesisStakedFunds}
+	}
+	return t.InitialStakedFunds
+}
+
+// GetStartTime returns the Unix time the network starts at: startTime,
+// or startTimeOffset before createdAt if not given
+func (t *GenesisTemplate) GetStartTime(createdAt time.Time) int64 {
+	if t != nil && t.StartTime != 0 {
+		return t.StartTime
+	}
+	offset := DefaultGenesisStartTimeOffset
+	if t != nil && t.StartTimeOffset != nil {
+		offset = t.StartTimeOffset.Duration
+	}
+	return createdAt.Add(-offset).Unix()
+}
+
+// GetInitialStakeDuration returns initialStakeDuration, or the default duration if not given
+func (t *GenesisTemplate) GetInitialStakeDuration() int64 {
+	if t == nil || t.InitialStakeDuration == 0 {
+		return DefaultGenesisInitialStakeDuration
+	}
+	return t.InitialStakeDuration
+}
+
+// GetInitialStakeDurationOffset returns initialStakeDurationOffset, or the default offset if not given
+func (t *GenesisTemplate) GetInitialStakeDurationOffset() int64 {
+	if t == nil || t.InitialStakeDurationOffset == 0 {
+		return DefaultGenesisInitialStakeDurationOffset
+	}
+	return t.InitialStakeDurationOffset
+}
+
+// IsCustomNetwork returns true if the network has its own genesis, instead of one built into avalanchego
+func (s *AvalanchegoSpec) IsCustomNetwork() bool {
+	return isCustomNetworkID(s.GetNetworkID())
+}
+
+// JoinsPublicNetwork returns true if the nodes bootstrap from the public beacons of mainnet or fuji,
+// no bootstrapper being given
+func (s *AvalanchegoSpec) JoinsPublicNetwork() bool {
+	networkID := s.GetNetworkID()
+	return (networkID == avalanchegoConstants.MainnetID || networkID == avalanchegoConstants.FujiID) &&
+		s.BootstrapperURL == "" &&
+		len(s.Bootstrappers) == 0
+}
+
+// AttachesToNetwork returns true if the nodes join a network running outside of this deployment,
+// through bootstrapperURL, external bootstrappers or the public beacons
+func (s *AvalanchegoSpec) AttachesToNetwork() bool {
+	if s.BootstrapperURL != "" || s.JoinsPublicNetwork() {
+		return true
+	}
+	for _, b := range s.Bootstrappers {
+		if b.Address != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// envNetworkID returns the network ID given with AVAGO_NETWORK_ID in env.
+// The second value is false if it isn't set, or can't be parsed.
+func (s *AvalanchegoSpec) envNetworkID() (uint32, bool) {
+	i := indexOfEnv(s.Env, "AVAGO_NETWORK_ID")
+	if i == -1 {
+		return 0, false
+	}
+	networkID, err := strconv.ParseUint(s.Env[i].Value, 10, 32)
+	if err != nil || networkID == 0 {
+		return 0, false
+	}
+	return uint32(networkID), true
+}
+
+// isEthAddress returns true if address is a C-Chain address, e.g. 0xb3d82b1367d362de99ab59a658165aff520cbd4d
+func isEthAddress(address string) bool {
+	if !strings.HasPrefix(address, "0x") {
+		return false
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(address, "0x"))
+	return err == nil && len(b) == 20
+}
+
+// validateXChainAddress returns an error if address isn't an X-Chain address of the network with the given HRP,
+// e.g. X-custom18jma8ppw3nhx5r4ap8clazz0dps7rv5u9xde7p
+func validateXChainAddress(address string, hrp string) error {
+	chain, addressHRP, b, err := formatting.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	if chain != "X" {
+		return fmt.Errorf("must be an X-Chain address, not %s", chain)
+	}
+	if addressHRP != hrp {
+		return fmt.Errorf("must be an address of the %s network, not %s", hrp, addressHRP)
+	}
+	if len(b) != 20 {
+		return fmt.Errorf("must be 20 bytes long, not %d", len(b))
+	}
+	return nil
+}
+
+// networkPresetOf returns the name of the network with the given ID, custom unless it is well-known
+func networkPresetOf(networkID uint32) NetworkPreset {
+	for preset, id := range networkPresetIDs {
+		if id == networkID {
+			return preset
+		}
+	}
+	return CustomNetwork
+}
+
+// FilterReservedEnv returns env without the variables listed in ReservedEnvVars,
+// and the names of the variables it removed
+func FilterReservedEnv(env []corev1.EnvVar) ([]corev1.EnvVar, []string) {
+	var (
+		allowed []corev1.EnvVar
+		removed []string
+	)
+	for _, v := range env {
+		if isReservedEnvVar(v.Name) {
+			removed = append(removed, v.Name)
+			continue
+		}
+		allowed = append(allowed, v)
+	}
+	return allowed, removed
+}
+
+func isReservedEnvVar(name string) bool {
+	for _, v := range ReservedEnvVars {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns -1 if not found, index otherwise
+func indexOfEnv(env []corev1.EnvVar, name string) int {
+	for i, v := range env {
+		if v.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func isCustomNetworkID(networkID uint32) bool {
+	return networkID != avalanchegoConstants.MainnetID &&
+		networkID != avalanchegoConstants.FujiID &&
+		networkID != avalanchegoConstants.LocalID
+}
@@ -0,0 +1,615 @@
+package v1alpha1
+
+import (
+	"encoding/base64"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Avalanchego webhook", func() {
+	newInstance := func(spec AvalanchegoSpec) *Avalanchego {
+		return &Avalanchego{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "avalanchego-test-validator",
+				Namespace: "default",
+			},
+			Spec: spec,
+		}
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte("pem"))
+
+	Context("Defaulting", func() {
+		It("Should fill in image, tag, node count and network ID", func() {
+			instance := newInstance(AvalanchegoSpec{})
+			instance.Default()
+
+			Expect(instance.Spec.Image).Should(Equal("avaplatform/avalanchego"))
+			Expect(instance.Spec.Tag).Should(Equal("latest"))
+			Expect(instance.Spec.NodeCount).Should(Equal(5))
+			Expect(instance.Spec.Network).Should(Equal(CustomNetwork))
+			Expect(instance.Spec.NetworkID).Should(Equal(uint32(12346)))
+			Expect(instance.Spec.Storage.Size.String()).Should(Equal("50Gi"))
+		})
+
+		It("Should fill in defaults on a copy of the spec", func() {
+			spec := AvalanchegoSpec{DataSource: &DataSourceSpec{Archive: &ArchiveSource{URL: "https://example.com/db.tar"}}}
+			defaulted := spec.WithDefaults()
+
+			Expect(defaulted.Storage.AccessModes).Should(Equal([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}))
+			Expect(defaulted.UpgradeStrategy.MaxUnavailable).Should(Equal(1))
+			Expect(defaulted.DataSource.Archive.Image).Should(Equal(DefaultArchiveImage))
+			Expect(spec.Storage.Size).Should(BeNil())
+			Expect(spec.DataSource.Archive.Image).Should(BeEmpty())
+		})
+
+		It("Should keep 3 backups by default", func() {
+			instance := newInstance(AvalanchegoSpec{Backup: &BackupSpec{Schedule: "@daily"}})
+			instance.Default()
+
+			Expect(instance.Spec.Backup.Keep).Should(Equal(3))
+		})
+
+		It("Should upgrade one node at a time by default", func() {
+			instance := newInstance(AvalanchegoSpec{UpgradeStrategy: UpgradeStrategy{Type: CanaryStrategy}})
+			instance.Default()
+
+			Expect(instance.Spec.UpgradeStrategy.MaxUnavailable).Should(Equal(1))
+			Expect(instance.Spec.UpgradeStrategy.FailurePolicy).Should(Equal(PauseOnFailure))
+			Expect(instance.Spec.UpgradeStrategy.Canary).Should(Equal(&CanarySpec{Nodes: 1}))
+		})
+
+		It("Should fill in the ID of a well-known network", func() {
+			instance := newInstance(AvalanchegoSpec{Network: FujiNetwork})
+			instance.Default()
+
+			Expect(instance.Spec.NetworkID).Should(Equal(uint32(5)))
+		})
+
+		It("Should move a network ID given in env into the spec", func() {
+			instance := newInstance(AvalanchegoSpec{
+				Env: []corev1.EnvVar{{Name: "AVAGO_NETWORK_ID", Value: "5"}},
+			})
+			instance.Default()
+
+			Expect(instance.Spec.Network).Should(Equal(FujiNetwork))
+			Expect(instance.Spec.NetworkID).Should(Equal(uint32(5)))
+			Expect(instance.Spec.Env).Should(BeEmpty())
+		})
+
+		It("Should move reserved environment variables out of the spec", func() {
+			instance := newInstance(AvalanchegoSpec{
+				Env: []corev1.EnvVar{
+					{Name: "AVAGO_PUBLIC_IP", Value: "1.2.3.4"},
+					{Name: "AVAGO_DB_DIR", Value: "/tmp"},
+					{Name: "AVAGO_LOG_LEVEL", Value: "debug"},
+					{Name: "AVAGO_GENESIS", Value: "/tmp/genesis.json"},
+				},
+			})
+			instance.Default()
+
+			Expect(instance.Spec.Env).Should(Equal([]corev1.EnvVar{
+				{Name: "AVAGO_LOG_LEVEL", Value: "debug"},
+			}))
+			Expect(instance.Annotations[RemovedEnvAnnotation]).Should(HavePrefix("AVAGO_PUBLIC_IP,AVAGO_DB_DIR,AVAGO_GENESIS:"))
+
+			// The annotation goes away once the spec has no reserved variable left
+			instance.Default()
+			Expect(instance.Annotations).ShouldNot(HaveKey(RemovedEnvAnnotation))
+		})
+	})
+
+	Context("Validating new objects", func() {
+		It("Should accept a new network", func() {
+			Expect(newInstance(AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      5,
+			}).ValidateCreate()).Should(Succeed())
+		})
+
+		It("Should reject certificates not matching nodeCount", func() {
+			err := newInstance(AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      2,
+				Genesis:        "{}",
+				Certificates:   []Certificate{{Cert: encoded, Key: encoded}},
+			}).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+		})
+
+		It("Should reject certificates which are not base64 encoded", func() {
+			err := newInstance(AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				Genesis:        "{}",
+				Certificates:   []Certificate{{Cert: "-----BEGIN CERTIFICATE-----", Key: encoded}},
+			}).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.certificates[0].cert"))
+		})
+
+		It("Should reject pre-defined secrets combined with genesis or certificates", func() {
+			err := newInstance(AvalanchegoSpec{
+				DeploymentName:  "test-validator",
+				NodeCount:       1,
+				ExistingSecrets: []string{"test-secret-1"},
+				Genesis:         "{}",
+				Certificates:    []Certificate{{Cert: encoded, Key: encoded}},
+			}).ValidateCreate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.genesis"))
+			Expect(err.Error()).Should(ContainSubstring("spec.certificates"))
+		})
+
+		It("Should require genesis to attach to a custom network", func() {
+			spec := AvalanchegoSpec{
+				DeploymentName:  "test-worker",
+				NodeCount:       1,
+				BootstrapperURL: "avago-test-validator-0-service",
+			}
+			Expect(newInstance(spec).ValidateCreate()).ShouldNot(Succeed())
+
+			spec.Network = FujiNetwork
+			Expect(newInstance(spec).ValidateCreate()).Should(Succeed())
+		})
+
+		It("Should reject a network ID not matching the network", func() {
+			err := newInstance(AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				Network:        MainnetNetwork,
+				NetworkID:      5,
+			}).ValidateCreate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.networkID"))
+
+			err = newInstance(AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				Network:        CustomNetwork,
+				NetworkID:      1,
+			}).ValidateCreate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.networkID"))
+		})
+
+		It("Should reject a genesis of another network", func() {
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				NetworkID:      1000,
+				Genesis:        `{"networkID":12346}`,
+				Certificates:   []Certificate{{Cert: encoded, Key: encoded}},
+			}
+			err := newInstance(spec).ValidateCreate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.genesis"))
+
+			spec.Network = LocalNetwork
+			spec.NetworkID = 0
+			err = newInstance(spec).ValidateCreate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("only custom networks have a genesis"))
+		})
+
+		It("Should reject a genesis which is not valid JSON", func() {
+			err := newInstance(AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				NetworkID:      1000,
+				Genesis:        `not json`,
+				Certificates:   []Certificate{{Cert: encoded, Key: encoded}},
+			}).ValidateCreate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.genesis"))
+			Expect(err.Error()).Should(ContainSubstring("must be valid JSON"))
+		})
+
+		It("Should reject teardown options that have no effect", func() {
+			err := newInstance(AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				AdoptSecrets:   true,
+				Teardown:       TeardownSpec{VolumeSnapshotClassName: "csi-snapclass"},
+			}).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.adoptSecrets"))
+			Expect(err.Error()).Should(ContainSubstring("spec.teardown.volumeSnapshotClassName"))
+		})
+
+		It("Should reject PVC settings combined with emptyDir", func() {
+			storageClassName := "standard"
+			err := newInstance(AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				Storage: StorageSpec{
+					EmptyDir:         true,
+					StorageClassName: &storageClassName,
+				},
+			}).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.storage.storageClassName"))
+		})
+
+		It("Should require a maxSize above size for automatic expansion", func() {
+			size := resource.MustParse("100Gi")
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				Storage: StorageSpec{
+					Size:       &size,
+					AutoExpand: &AutoExpandSpec{ThresholdPercent: 80},
+				},
+			}
+			err := newInstance(spec).ValidateCreate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.storage.autoExpand.maxSize"))
+
+			spec.Storage.AutoExpand.MaxSize = resource.MustParse("50Gi")
+			Expect(newInstance(spec).ValidateCreate()).ShouldNot(Succeed())
+
+			spec.Storage.AutoExpand.MaxSize = resource.MustParse("200Gi")
+			Expect(newInstance(spec).ValidateCreate()).Should(Succeed())
+		})
+
+		It("Should reject invalid backups", func() {
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      2,
+				Backup:         &BackupSpec{Schedule: "0 3 * *", Node: 2},
+			}
+			err := newInstance(spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.backup.schedule"))
+			Expect(err.Error()).Should(ContainSubstring("spec.backup.node"))
+
+			spec.Backup = &BackupSpec{Schedule: "@daily", Node: 1}
+			Expect(newInstance(spec).ValidateCreate()).Should(Succeed())
+
+			spec.Storage.EmptyDir = true
+			Expect(newInstance(spec).ValidateCreate()).ShouldNot(Succeed())
+		})
+
+		It("Should require exactly one valid data source", func() {
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				DataSource: &DataSourceSpec{
+					VolumeSnapshot: "avago-test-validator-0-pvc-20211001-030000",
+					Archive:        &ArchiveSource{URL: "s3://bucket/db.tar", SHA256: "abc"},
+				},
+			}
+			err := newInstance(spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("exactly one"))
+			Expect(err.Error()).Should(ContainSubstring("spec.dataSource.archive.url"))
+			Expect(err.Error()).Should(ContainSubstring("spec.dataSource.archive.sha256"))
+
+			spec.DataSource = &DataSourceSpec{Archive: &ArchiveSource{
+				URL:    "https://snapshots.example.com/db.tar.gz",
+				SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			}}
+			Expect(newInstance(spec).ValidateCreate()).Should(Succeed())
+		})
+
+		It("Should only accept canary settings with the Canary strategy", func() {
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      3,
+				UpgradeStrategy: UpgradeStrategy{
+					Type:   RollingUpdateStrategy,
+					Canary: &CanarySpec{Nodes: 1},
+				},
+			}
+			err := newInstance(spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.upgradeStrategy.canary"))
+
+			spec.UpgradeStrategy.Type = CanaryStrategy
+			Expect(newInstance(spec).ValidateCreate()).Should(Succeed())
+		})
+
+		It("Should reject negative probe thresholds", func() {
+			err := newInstance(AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				Probes: ProbesSpec{
+					Readiness: ProbeSpec{FailureThreshold: -1},
+				},
+			}).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.probes.readiness.failureThreshold"))
+		})
+
+		It("Should reject invalid bootstrappers", func() {
+			node := func(i int) *int { return &i }
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      3,
+				Bootstrappers: []BootstrapperSpec{
+					{Node: node(0)},
+					{Node: node(3)},
+					{Address: "10.0.0.1"},
+				},
+			}
+			err := newInstance(spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.bootstrappers[1].node"))
+			Expect(err.Error()).Should(ContainSubstring("spec.bootstrappers[2].address"))
+			Expect(err.Error()).Should(ContainSubstring("spec.bootstrappers[2].nodeID"))
+
+			spec.Bootstrappers = []BootstrapperSpec{{Node: node(0)}, {Node: node(1)}, {Node: node(2)}}
+			Expect(newInstance(spec).ValidateCreate()).Should(Succeed())
+
+			spec.BootstrapperURL = "avago-test-validator-0-service"
+			Expect(newInstance(spec).ValidateCreate()).ShouldNot(Succeed())
+		})
+
+		It("Should reject invalid genesis templates", func() {
+			fee := 20000
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      3,
+				GenesisTemplate: &GenesisTemplate{
+					Allocations: []GenesisAllocation{{
+						EthAddr:        "0xb3d82b1367d362de99ab59a658165aff520cbd4d",
+						DjtxAddr:       "X-custom1g65uqn6t77p656w64023nh8nd9updzmxwd59gh",
+						UnlockSchedule: []GenesisUnlock{{Amount: 10000000000000000, Locktime: 1633824000}},
+					}},
+					InitialStakedFunds:         []string{"X-custom1g65uqn6t77p656w64023nh8nd9updzmxwd59gh"},
+					InitialStakeDuration:       7200,
+					InitialStakeDurationOffset: 600,
+					Stakers:                    []GenesisStaker{{DelegationFee: &fee}},
+				},
+			}
+			Expect(newInstance(spec).ValidateCreate()).Should(Succeed())
+
+			spec.GenesisTemplate.Allocations[0].EthAddr = "b3d82b1367d362de99ab59a658165aff520cbd4d"
+			spec.GenesisTemplate.InitialStakedFunds = []string{"X-custom18jma8ppw3nhx5r4ap8clazz0dps7rv5u9xde7p"}
+			spec.GenesisTemplate.Stakers[0].RewardAddress = "P-custom18jma8ppw3nhx5r4ap8clazz0dps7rv5u9xde7p"
+			spec.GenesisTemplate.InitialStakeDurationOffset = 3600
+			err := newInstance(spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.genesisTemplate.allocations[0].ethAddr"))
+			Expect(err.Error()).Should(ContainSubstring("spec.genesisTemplate.initialStakedFunds[0]"))
+			Expect(err.Error()).Should(ContainSubstring("spec.genesisTemplate.stakers[0].rewardAddress"))
+			Expect(err.Error()).Should(ContainSubstring("spec.genesisTemplate.initialStakeDuration"))
+
+			err = newInstance(AvalanchegoSpec{
+				DeploymentName:  "test-validator",
+				NodeCount:       1,
+				Genesis:         "{}",
+				Certificates:    []Certificate{{Cert: encoded, Key: encoded}},
+				GenesisTemplate: &GenesisTemplate{Message: "hello"},
+			}).ValidateCreate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.genesisTemplate"))
+		})
+
+		It("Should reject genesis start times leaving no stake", func() {
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				GenesisTemplate: &GenesisTemplate{
+					StartTime:       1600000000,
+					StartTimeOffset: &metav1.Duration{Duration: time.Hour},
+				},
+			}
+			err := newInstance(spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.genesisTemplate.startTimeOffset"))
+			Expect(err.Error()).Should(ContainSubstring("the stakes of the initial stakers would be over"))
+
+			spec.GenesisTemplate = &GenesisTemplate{StartTimeOffset: &metav1.Duration{Duration: -time.Hour}}
+			err = newInstance(spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.genesisTemplate.startTimeOffset"))
+
+			spec.GenesisTemplate = &GenesisTemplate{StartTimeOffset: &metav1.Duration{Duration: 24 * time.Hour}}
+			Expect(newInstance(spec).ValidateCreate()).Should(Succeed())
+		})
+
+		It("Should only check the genesis start time on creation", func() {
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				GenesisTemplate: &GenesisTemplate{
+					StartTime:            1600000000,
+					InitialStakeDuration: 3600,
+				},
+			}
+			old := newInstance(spec)
+			err := old.ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("the stakes of the initial stakers would be over"))
+
+			By("Letting the network be updated and deleted once the stakes are over")
+			Expect(old.ValidateSpec()).Should(Succeed())
+			updated := old.DeepCopy()
+			updated.Spec.Tag = "v1.6.4"
+			Expect(updated.ValidateUpdate(old)).Should(Succeed())
+
+			spec.GenesisTemplate = &GenesisTemplate{StartTime: time.Now().Add(time.Hour).Unix()}
+			err = newInstance(spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.genesisTemplate.startTime"))
+			Expect(newInstance(spec).ValidateSpec()).Should(Succeed())
+		})
+
+		It("Should only check the offsets between the initial stakers on creation", func() {
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      2,
+				GenesisTemplate: &GenesisTemplate{
+					InitialStakeDuration:       86400,
+					InitialStakeDurationOffset: 43200,
+				},
+			}
+			old := newInstance(spec)
+			Expect(old.ValidateCreate()).Should(Succeed())
+
+			By("Letting the network be scaled up, added nodes are not initial stakers")
+			scaled := old.DeepCopy()
+			scaled.Spec.NodeCount = 5
+			Expect(scaled.ValidateUpdate(old)).Should(Succeed())
+			Expect(scaled.ValidateSpec()).Should(Succeed())
+
+			err := newInstance(scaled.Spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.genesisTemplate.initialStakeDuration"))
+			Expect(err.Error()).Should(ContainSubstring("between 5 stakers"))
+		})
+
+		It("Should reject an invalid C-Chain", func() {
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				CChain: &CChainSpec{
+					Alloc: map[string]CChainAccount{
+						"8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC":   {Balance: "1"},
+						"0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC": {Balance: "lots"},
+					},
+					FeeConfig:   &CChainFeeConfig{TargetBlockRate: 2, MinBaseFee: 1, TargetGas: 1, BaseFeeChangeDenominator: 1, MinBlockGasCost: 2, MaxBlockGasCost: 1},
+					Precompiles: CChainPrecompiles{FeeManager: &CChainAllowList{AdminAddresses: []string{"0x1234"}}},
+				},
+			}
+			err := newInstance(spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.cChain.alloc[8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC]"))
+			Expect(err.Error()).Should(ContainSubstring("spec.cChain.alloc[0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC].balance"))
+			Expect(err.Error()).Should(ContainSubstring("spec.cChain.feeConfig.maxBlockGasCost"))
+			Expect(err.Error()).Should(ContainSubstring("spec.cChain.precompiles.feeManager.adminAddresses[0]"))
+
+			// Only the config of the C-Chain applies to an existing network
+			spec = AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				Network:        FujiNetwork,
+				CChain:         &CChainSpec{Config: &CChainConfig{LogLevel: "debug"}},
+			}
+			Expect(newInstance(spec).ValidateCreate()).Should(Succeed())
+			spec.CChain.ChainID = 1
+			Expect(newInstance(spec).ValidateCreate()).ShouldNot(Succeed())
+		})
+
+		It("Should reject a chain config dir in env combined with the C-Chain config", func() {
+			spec := AvalanchegoSpec{
+				DeploymentName: "test-validator",
+				NodeCount:      1,
+				Env:            []corev1.EnvVar{{Name: "AVAGO_CHAIN_CONFIG_DIR", Value: "/data/chains"}},
+			}
+			Expect(newInstance(spec).ValidateCreate()).Should(Succeed())
+
+			spec.CChain = &CChainSpec{Config: &CChainConfig{LogLevel: "debug"}}
+			err := newInstance(spec).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("spec.cChain.config"))
+		})
+	})
+
+	Context("Validating updates", func() {
+		It("Should reject a changed deploymentName", func() {
+			old := newInstance(AvalanchegoSpec{DeploymentName: "test-validator", NodeCount: 5})
+			updated := old.DeepCopy()
+			updated.Spec.DeploymentName = "test-validator-2"
+			Expect(updated.ValidateUpdate(old)).ShouldNot(Succeed())
+
+			updated.Spec.DeploymentName = old.Spec.DeploymentName
+			updated.Spec.Tag = "v1.6.4"
+			Expect(updated.ValidateUpdate(old)).Should(Succeed())
+		})
+
+		It("Should reject a changed genesis template", func() {
+			old := newInstance(AvalanchegoSpec{DeploymentName: "test-validator", NodeCount: 5, GenesisTemplate: &GenesisTemplate{Message: "hello"}})
+			updated := old.DeepCopy()
+			updated.Spec.GenesisTemplate.Message = "world"
+			err := updated.ValidateUpdate(old)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.genesisTemplate"))
+		})
+
+		It("Should only accept changes to the C-Chain config", func() {
+			old := newInstance(AvalanchegoSpec{DeploymentName: "test-validator", NodeCount: 5, CChain: &CChainSpec{ChainID: 99999}})
+			updated := old.DeepCopy()
+			updated.Spec.CChain.Config = &CChainConfig{LogLevel: "debug"}
+			Expect(updated.ValidateUpdate(old)).Should(Succeed())
+			updated.Spec.CChain.ChainID = 12345
+			err := updated.ValidateUpdate(old)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.cChain"))
+		})
+
+		It("Should reject a changed network ID", func() {
+			old := newInstance(AvalanchegoSpec{DeploymentName: "test-validator", NodeCount: 5})
+			old.Default()
+			updated := old.DeepCopy()
+			updated.Spec.NetworkID = 1000
+			err := updated.ValidateUpdate(old)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.networkID"))
+		})
+
+		It("Should reject a changed network ID of an instance created without defaults", func() {
+			old := newInstance(AvalanchegoSpec{DeploymentName: "test-validator", NodeCount: 5})
+			updated := old.DeepCopy()
+			updated.Default()
+			Expect(updated.ValidateUpdate(old)).Should(Succeed())
+
+			updated.Spec.NetworkID = 1000
+			err := updated.ValidateUpdate(old)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.networkID"))
+
+			updated = old.DeepCopy()
+			updated.Spec.Network = FujiNetwork
+			err = updated.ValidateUpdate(old)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.networkID"))
+		})
+
+		It("Should refuse removing the first node or going below minValidators", func() {
+			old := newInstance(AvalanchegoSpec{DeploymentName: "test-validator", NodeCount: 5, MinValidators: 3})
+			updated := old.DeepCopy()
+			updated.Spec.NodeCount = 3
+			Expect(updated.ValidateUpdate(old)).Should(Succeed())
+
+			updated.Spec.NodeCount = 2
+			Expect(updated.ValidateUpdate(old)).ShouldNot(Succeed())
+
+			updated.Spec.MinValidators = 0
+			updated.Spec.NodeCount = 0
+			err := updated.ValidateUpdate(old)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.nodeCount"))
+		})
+
+		It("Should reject toggling emptyDir", func() {
+			old := newInstance(AvalanchegoSpec{DeploymentName: "test-validator", NodeCount: 5})
+			updated := old.DeepCopy()
+			updated.Spec.Storage.EmptyDir = true
+			err := updated.ValidateUpdate(old)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.storage.emptyDir"))
+		})
+
+		It("Should refuse shrinking the storage", func() {
+			size := resource.MustParse("100Gi")
+			old := newInstance(AvalanchegoSpec{DeploymentName: "test-validator", NodeCount: 1, Storage: StorageSpec{Size: &size}})
+			updated := old.DeepCopy()
+			larger := resource.MustParse("200Gi")
+			updated.Spec.Storage.Size = &larger
+			Expect(updated.ValidateUpdate(old)).Should(Succeed())
+
+			smaller := resource.MustParse("50Gi")
+			updated.Spec.Storage.Size = &smaller
+			err := updated.ValidateUpdate(old)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("spec.storage.size"))
+		})
+	})
+})
@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cron schedule", func() {
+	// A Monday
+	from := time.Date(2021, time.March, 1, 10, 30, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2021, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	table.DescribeTable("Next",
+		func(spec string, expected time.Time) {
+			schedule, err := ParseCronSchedule(spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(schedule.Next(from)).Should(Equal(expected))
+		},
+		table.Entry("every minute", "* * * * *", at(time.March, 1, 10, 31)),
+		table.Entry("later the same day", "0 12 * * *", at(time.March, 1, 12, 0)),
+		table.Entry("the next day", "0 3 * * *", at(time.March, 2, 3, 0)),
+		table.Entry("a macro", "@daily", at(time.March, 2, 0, 0)),
+		table.Entry("surrounding spaces", "  @hourly ", at(time.March, 1, 11, 0)),
+		table.Entry("a list", "15,45 * * * *", at(time.March, 1, 10, 45)),
+		table.Entry("a range", "0 1-3 * * *", at(time.March, 2, 1, 0)),
+		table.Entry("a step", "*/20 * * * *", at(time.March, 1, 10, 40)),
+		table.Entry("a step over a range", "10-50/15 * * * *", at(time.March, 1, 10, 40)),
+		table.Entry("a step from a value", "50/5 * * * *", at(time.March, 1, 10, 50)),
+		table.Entry("a day of month", "0 0 15 * *", at(time.March, 15, 0, 0)),
+		table.Entry("a month", "0 0 1 6 *", at(time.June, 1, 0, 0)),
+		table.Entry("a day of week", "0 0 * * 5", at(time.March, 5, 0, 0)),
+		table.Entry("Sunday as 7", "0 0 * * 7", at(time.March, 7, 0, 0)),
+		table.Entry("Sunday as 0", "0 0 * * 0", at(time.March, 7, 0, 0)),
+		table.Entry("either the day of month or the day of week", "0 0 20 * 3", at(time.March, 3, 0, 0)),
+		table.Entry("both days with a wildcard day of month", "0 0 */2 * 4", at(time.March, 11, 0, 0)),
+		table.Entry("the next year", "0 0 1 1 *", time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		table.Entry("the next leap day", "0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)),
+		table.Entry("a date which never exists", "0 0 30 2 *", time.Time{}),
+	)
+
+	table.DescribeTable("Invalid schedules",
+		func(spec string) {
+			_, err := ParseCronSchedule(spec)
+			Expect(err).To(HaveOccurred())
+		},
+		table.Entry("empty", ""),
+		table.Entry("too few fields", "* * * *"),
+		table.Entry("too many fields", "* * * * * *"),
+		table.Entry("an unknown macro", "@weekdays"),
+		table.Entry("a minute out of range", "60 * * * *"),
+		table.Entry("an hour out of range", "0 24 * * *"),
+		table.Entry("a day of month of 0", "0 0 0 * *"),
+		table.Entry("a month out of range", "0 0 1 13 *"),
+		table.Entry("a day of week out of range", "0 0 * * 8"),
+		table.Entry("a reversed range", "0 5-1 * * *"),
+		table.Entry("a step of 0", "*/0 * * * *"),
+		table.Entry("a missing step", "*/ * * * *"),
+		table.Entry("a name", "0 0 * * MON"),
+		table.Entry("an empty list item", "1,,2 * * * *"),
+	)
+
+	It("Should keep the time zone of the given time", func() {
+		schedule, err := ParseCronSchedule("0 3 * * *")
+		Expect(err).ToNot(HaveOccurred())
+		loc := time.FixedZone("UTC+2", 2*60*60)
+		Expect(schedule.Next(from.In(loc))).Should(Equal(time.Date(2021, time.March, 2, 3, 0, 0, 0, loc)))
+	})
+})
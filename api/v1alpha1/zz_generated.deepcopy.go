@@ -12,9 +12,46 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArchiveSource) DeepCopyInto(out *ArchiveSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArchiveSource.
+func (in *ArchiveSource) DeepCopy() *ArchiveSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ArchiveSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoExpandSpec) DeepCopyInto(out *AutoExpandSpec) {
+	*out = *in
+	if in.Step != nil {
+		in, out := &in.Step, &out.Step
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	out.MaxSize = in.MaxSize.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoExpandSpec.
+func (in *AutoExpandSpec) DeepCopy() *AutoExpandSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoExpandSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Avalanchego) DeepCopyInto(out *Avalanchego) {
 	*out = *in
@@ -77,6 +114,23 @@ func (in *AvalanchegoList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AvalanchegoSpec) DeepCopyInto(out *AvalanchegoSpec) {
 	*out = *in
+	if in.Bootstrappers != nil {
+		in, out := &in.Bootstrappers, &out.Bootstrappers
+		*out = make([]BootstrapperSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GenesisTemplate != nil {
+		in, out := &in.GenesisTemplate, &out.GenesisTemplate
+		*out = new(GenesisTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CChain != nil {
+		in, out := &in.CChain, &out.CChain
+		*out = new(CChainSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ExistingSecrets != nil {
 		in, out := &in.ExistingSecrets, &out.ExistingSecrets
 		*out = make([]string, len(*in))
@@ -114,6 +168,20 @@ func (in *AvalanchegoSpec) DeepCopyInto(out *AvalanchegoSpec) {
 			(*out)[key] = val
 		}
 	}
+	out.Teardown = in.Teardown
+	in.Storage.DeepCopyInto(&out.Storage)
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(BackupSpec)
+		**out = **in
+	}
+	if in.DataSource != nil {
+		in, out := &in.DataSource, &out.DataSource
+		*out = new(DataSourceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.UpgradeStrategy.DeepCopyInto(&out.UpgradeStrategy)
+	out.Probes = in.Probes
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AvalanchegoSpec.
@@ -134,6 +202,52 @@ func (in *AvalanchegoStatus) DeepCopyInto(out *AvalanchegoStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.GenesisStartTime != nil {
+		in, out := &in.GenesisStartTime, &out.GenesisStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Teardown != nil {
+		in, out := &in.Teardown, &out.Teardown
+		*out = new(TeardownStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VolumeExpansions != nil {
+		in, out := &in.VolumeExpansions, &out.VolumeExpansions
+		*out = make([]VolumeExpansion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Backups != nil {
+		in, out := &in.Backups, &out.Backups
+		*out = make([]Backup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastBackupTime != nil {
+		in, out := &in.LastBackupTime, &out.LastBackupTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AvalanchegoStatus.
@@ -146,6 +260,234 @@ func (in *AvalanchegoStatus) DeepCopy() *AvalanchegoStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Backup) DeepCopyInto(out *Backup) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.SnapshotTime != nil {
+		in, out := &in.SnapshotTime, &out.SnapshotTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Backup.
+func (in *Backup) DeepCopy() *Backup {
+	if in == nil {
+		return nil
+	}
+	out := new(Backup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupSpec) DeepCopyInto(out *BackupSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSpec.
+func (in *BackupSpec) DeepCopy() *BackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapperSpec) DeepCopyInto(out *BootstrapperSpec) {
+	*out = *in
+	if in.Node != nil {
+		in, out := &in.Node, &out.Node
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapperSpec.
+func (in *BootstrapperSpec) DeepCopy() *BootstrapperSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapperSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CChainAccount) DeepCopyInto(out *CChainAccount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CChainAccount.
+func (in *CChainAccount) DeepCopy() *CChainAccount {
+	if in == nil {
+		return nil
+	}
+	out := new(CChainAccount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CChainAllowList) DeepCopyInto(out *CChainAllowList) {
+	*out = *in
+	if in.AdminAddresses != nil {
+		in, out := &in.AdminAddresses, &out.AdminAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EnabledAddresses != nil {
+		in, out := &in.EnabledAddresses, &out.EnabledAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CChainAllowList.
+func (in *CChainAllowList) DeepCopy() *CChainAllowList {
+	if in == nil {
+		return nil
+	}
+	out := new(CChainAllowList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CChainConfig) DeepCopyInto(out *CChainConfig) {
+	*out = *in
+	if in.PruningEnabled != nil {
+		in, out := &in.PruningEnabled, &out.PruningEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LocalTxsEnabled != nil {
+		in, out := &in.LocalTxsEnabled, &out.LocalTxsEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SnowmanAPIEnabled != nil {
+		in, out := &in.SnowmanAPIEnabled, &out.SnowmanAPIEnabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CChainConfig.
+func (in *CChainConfig) DeepCopy() *CChainConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CChainConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CChainFeeConfig) DeepCopyInto(out *CChainFeeConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CChainFeeConfig.
+func (in *CChainFeeConfig) DeepCopy() *CChainFeeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CChainFeeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CChainPrecompiles) DeepCopyInto(out *CChainPrecompiles) {
+	*out = *in
+	if in.ContractDeployerAllowList != nil {
+		in, out := &in.ContractDeployerAllowList, &out.ContractDeployerAllowList
+		*out = new(CChainAllowList)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TxAllowList != nil {
+		in, out := &in.TxAllowList, &out.TxAllowList
+		*out = new(CChainAllowList)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContractNativeMinter != nil {
+		in, out := &in.ContractNativeMinter, &out.ContractNativeMinter
+		*out = new(CChainAllowList)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FeeManager != nil {
+		in, out := &in.FeeManager, &out.FeeManager
+		*out = new(CChainAllowList)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CChainPrecompiles.
+func (in *CChainPrecompiles) DeepCopy() *CChainPrecompiles {
+	if in == nil {
+		return nil
+	}
+	out := new(CChainPrecompiles)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CChainSpec) DeepCopyInto(out *CChainSpec) {
+	*out = *in
+	if in.Alloc != nil {
+		in, out := &in.Alloc, &out.Alloc
+		*out = make(map[string]CChainAccount, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.FeeConfig != nil {
+		in, out := &in.FeeConfig, &out.FeeConfig
+		*out = new(CChainFeeConfig)
+		**out = **in
+	}
+	in.Precompiles.DeepCopyInto(&out.Precompiles)
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(CChainConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CChainSpec.
+func (in *CChainSpec) DeepCopy() *CChainSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CChainSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanarySpec) DeepCopyInto(out *CanarySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanarySpec.
+func (in *CanarySpec) DeepCopy() *CanarySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CanarySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Certificate) DeepCopyInto(out *Certificate) {
 	*out = *in
@@ -160,3 +502,371 @@ func (in *Certificate) DeepCopy() *Certificate {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataSourceSpec) DeepCopyInto(out *DataSourceSpec) {
+	*out = *in
+	if in.Archive != nil {
+		in, out := &in.Archive, &out.Archive
+		*out = new(ArchiveSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSourceSpec.
+func (in *DataSourceSpec) DeepCopy() *DataSourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DataSourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GenesisAllocation) DeepCopyInto(out *GenesisAllocation) {
+	*out = *in
+	if in.UnlockSchedule != nil {
+		in, out := &in.UnlockSchedule, &out.UnlockSchedule
+		*out = make([]GenesisUnlock, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenesisAllocation.
+func (in *GenesisAllocation) DeepCopy() *GenesisAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(GenesisAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GenesisStaker) DeepCopyInto(out *GenesisStaker) {
+	*out = *in
+	if in.DelegationFee != nil {
+		in, out := &in.DelegationFee, &out.DelegationFee
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenesisStaker.
+func (in *GenesisStaker) DeepCopy() *GenesisStaker {
+	if in == nil {
+		return nil
+	}
+	out := new(GenesisStaker)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GenesisTemplate) DeepCopyInto(out *GenesisTemplate) {
+	*out = *in
+	if in.Allocations != nil {
+		in, out := &in.Allocations, &out.Allocations
+		*out = make([]GenesisAllocation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InitialStakedFunds != nil {
+		in, out := &in.InitialStakedFunds, &out.InitialStakedFunds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTimeOffset != nil {
+		in, out := &in.StartTimeOffset, &out.StartTimeOffset
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Stakers != nil {
+		in, out := &in.Stakers, &out.Stakers
+		*out = make([]GenesisStaker, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenesisTemplate.
+func (in *GenesisTemplate) DeepCopy() *GenesisTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(GenesisTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GenesisUnlock) DeepCopyInto(out *GenesisUnlock) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenesisUnlock.
+func (in *GenesisUnlock) DeepCopy() *GenesisUnlock {
+	if in == nil {
+		return nil
+	}
+	out := new(GenesisUnlock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeHealth) DeepCopyInto(out *NodeHealth) {
+	*out = *in
+	in.LastChecked.DeepCopyInto(&out.LastChecked)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeHealth.
+func (in *NodeHealth) DeepCopy() *NodeHealth {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeHealth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
+	*out = *in
+	if in.StakeEndTime != nil {
+		in, out := &in.StakeEndTime, &out.StakeEndTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Health != nil {
+		in, out := &in.Health, &out.Health
+		*out = new(NodeHealth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Restore != nil {
+		in, out := &in.Restore, &out.Restore
+		*out = new(RestoreStatus)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeStatus.
+func (in *NodeStatus) DeepCopy() *NodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeSpec) DeepCopyInto(out *ProbeSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeSpec.
+func (in *ProbeSpec) DeepCopy() *ProbeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbesSpec) DeepCopyInto(out *ProbesSpec) {
+	*out = *in
+	out.Startup = in.Startup
+	out.Readiness = in.Readiness
+	out.Liveness = in.Liveness
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbesSpec.
+func (in *ProbesSpec) DeepCopy() *ProbesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreStatus) DeepCopyInto(out *RestoreStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreStatus.
+func (in *RestoreStatus) DeepCopy() *RestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeMode != nil {
+		in, out := &in.VolumeMode, &out.VolumeMode
+		*out = new(v1.PersistentVolumeMode)
+		**out = **in
+	}
+	if in.AutoExpand != nil {
+		in, out := &in.AutoExpand, &out.AutoExpand
+		*out = new(AutoExpandSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSpec.
+func (in *StorageSpec) DeepCopy() *StorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TeardownSpec) DeepCopyInto(out *TeardownSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TeardownSpec.
+func (in *TeardownSpec) DeepCopy() *TeardownSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TeardownSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TeardownStatus) DeepCopyInto(out *TeardownStatus) {
+	*out = *in
+	if in.Snapshots != nil {
+		in, out := &in.Snapshots, &out.Snapshots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TeardownStatus.
+func (in *TeardownStatus) DeepCopy() *TeardownStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TeardownStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]UpgradedNode, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStrategy) DeepCopyInto(out *UpgradeStrategy) {
+	*out = *in
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(CanarySpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStrategy.
+func (in *UpgradeStrategy) DeepCopy() *UpgradeStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradedNode) DeepCopyInto(out *UpgradedNode) {
+	*out = *in
+	in.UpgradeTime.DeepCopyInto(&out.UpgradeTime)
+	if in.HealthyTime != nil {
+		in, out := &in.HealthyTime, &out.HealthyTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradedNode.
+func (in *UpgradedNode) DeepCopy() *UpgradedNode {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradedNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeExpansion) DeepCopyInto(out *VolumeExpansion) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+	in.LastExpansionTime.DeepCopyInto(&out.LastExpansionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeExpansion.
+func (in *VolumeExpansion) DeepCopy() *VolumeExpansion {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeExpansion)
+	in.DeepCopyInto(out)
+	return out
+}
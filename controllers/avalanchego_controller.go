@@ -19,15 +19,24 @@ package controllers
 import (
 	"context"
 	"encoding/base64"
-	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
 	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
@@ -37,13 +46,16 @@ import (
 type AvalanchegoReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Used to check health and identity of running nodes
+	NodeAPI common.NodeAPI
+	// Used to report owned objects restored by the operator
+	Recorder record.EventRecorder
+	// Used to grow PVCs as they fill up
+	VolumeStats common.VolumeStatsSource
+	// Used to schedule backups
+	Clock clock.Clock
 }
 
-const (
-	createStsAsync asyncCreateStatefulSet = true
-	createStsSync  asyncCreateStatefulSet = false
-)
-
 //+kubebuilder:rbac:groups=chain.djtx.network,resources=avalanchegoes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=chain.djtx.network,resources=avalanchegoes/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=chain.djtx.network,resources=avalanchegoes/finalizers,verbs=update
@@ -52,6 +64,12 @@ const (
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods/status,verbs=get;patch
+//+kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=nodes/proxy,verbs=get
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;create;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -71,7 +89,7 @@ func (r *AvalanchegoReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		if errors.IsNotFound(err) {
 			l.Info("Not found so maybe deleted")
 			// Request object not found, could have been deleted after reconcile request.
-			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Owned objects are automatically garbage collected, after the teardown finalizer has removed the nodes.
 			// Return and don't requeue
 			return ctrl.Result{}, nil
 		}
@@ -79,134 +97,118 @@ func (r *AvalanchegoReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// NetworkMembersURI is mandatory field, if NetworkMembersURI has not been previously set up, then making it as empty struct
-	if reflect.ValueOf(instance.Status.NetworkMembersURI).IsZero() {
-		instance.Status.NetworkMembersURI = make([]string, 0)
-	}
-
-	//Pre flight checks
-	//Number of certs should match nodeCount
-	//TODO: move to validation webhook
-	if len(instance.Spec.Certificates) > 0 && len(instance.Spec.Certificates) != instance.Spec.NodeCount {
-		err = errors.NewBadRequest("Number of provided certificate does not match nodeCount")
-		instance.Status.Error = err.Error()
-		if err := r.Status().Update(ctx, instance); err != nil {
-			l.Error(err, "error calling Update")
+	// Nodes are removed in reverse order before the object goes away, see teardown
+	if !instance.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(instance, teardownFinalizer) {
+			return ctrl.Result{}, nil
 		}
-		return ctrl.Result{}, err
+		return r.teardown(ctx, instance, l)
 	}
-	//Number of secrets should match nodeCount
-	//TODO: move to validation webhook
-	if len(instance.Spec.ExistingSecrets) > 0 && len(instance.Spec.ExistingSecrets) != instance.Spec.NodeCount {
-		err = errors.NewBadRequest("Number of provided secrets does not match nodeCount")
-		instance.Status.Error = err.Error()
-		if err := r.Status().Update(ctx, instance); err != nil {
-			l.Error(err, "error calling Update")
+	if !controllerutil.ContainsFinalizer(instance, teardownFinalizer) {
+		controllerutil.AddFinalizer(instance, teardownFinalizer)
+		if err := r.Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, err
 	}
-	// Genesis should be inside secrets or omitted for mainnet, fuji or local networks
-	//TODO: move to validation webhook
-	if len(instance.Spec.ExistingSecrets) > 0 && instance.Spec.Genesis != "" {
-		err = errors.NewBadRequest("Genesis cannot be specified when using pre-defined secrets. genesis.json key should be avaliable in secret instead and AVAGO_GENESIS env var provided.")
-		instance.Status.Error = err.Error()
-		if err := r.Status().Update(ctx, instance); err != nil {
-			l.Error(err, "error calling Update")
-		}
-		return ctrl.Result{}, err
+
+	// NetworkMembersURI is mandatory field, if NetworkMembersURI has not been previously set up, then making it as empty struct
+	if reflect.ValueOf(instance.Status.NetworkMembersURI).IsZero() {
+		instance.Status.NetworkMembersURI = make([]string, 0)
 	}
-	// Certificates should be inside secrets
-	//TODO: move to validation webhook
-	if len(instance.Spec.ExistingSecrets) > 0 && len(instance.Spec.Certificates) > 0 {
-		err = errors.NewBadRequest("Certificates cannot be specified when using pre-defined secrets.")
-		instance.Status.Error = err.Error()
-		if err := r.Status().Update(ctx, instance); err != nil {
-			l.Error(err, "error calling Update")
-		}
-		return ctrl.Result{}, err
+	if instance.Status.Phase == "" {
+		instance.Status.Phase = chainv1alpha1.PhasePending
 	}
 
-	// Ignore these environment variables if given
-	for i, v := range instance.Spec.Env {
-		switch v.Name {
-		case "AVAGO_PUBLIC_IP", "AVAGO_HTTP_HOST", "AVAGO_STAKING_TLS_CERT_FILE", "AVAGO_STAKING_TLS_KEY_FILE", "AVAGO_DB_DIR", "AVAGO_HTTP_PORT", "AVAGO_STAKING_PORT", "AVAGO_GENESIS":
-			instance.Spec.Env[i] = instance.Spec.Env[len(instance.Spec.Env)-1]
-			instance.Spec.Env = instance.Spec.Env[:len(instance.Spec.Env)-1]
-		}
+	// Pre flight checks, the same ones the validating webhook runs
+	// Repeated here, since the operator may be deployed without webhooks
+	if err := instance.ValidateSpec(); err != nil {
+		return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonInvalidSpec, err, l)
 	}
 
-	var network common.Network
-	if (instance.Status.BootstrapperURL == "") &&
-		(instance.Spec.BootstrapperURL == "") &&
-		(instance.Spec.Genesis == "") &&
-		len(instance.Spec.ExistingSecrets) == 0 {
-		l.Info("Making new network")
+	// Ignore reserved environment variables if given, after reading a network ID given in env
+	// The defaulting webhook removes them from the stored object, but it may not be deployed
+	instance.Spec.DefaultNetwork()
+	instance.Spec.Env, _ = chainv1alpha1.FilterReservedEnv(instance.Spec.Env)
+
+	// Genesis and keys of a generated network are stored in the network Secret, before any node object is created
+	var (
+		network        common.Network
+		genesisStakers []string
+		problems       []string
+	)
+	if isGeneratedNetwork(instance) {
 		var err error
-		network, err = common.NewNetwork(instance.Spec.NodeCount)
-		if err != nil {
-			return ctrl.Result{}, fmt.Errorf("couldn't make new network")
+		if network, err = r.ensureNetwork(ctx, instance, l); err != nil {
+			return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonSecretsFailed, err, l)
+		}
+		if genesisStakers, err = common.GenesisStakers(network.Genesis); err != nil {
+			return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonSecretsFailed, err, l)
+		}
+		if problems, err = r.checkNetwork(ctx, instance, network); err != nil {
+			return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonSecretsFailed, err, l)
 		}
 	}
 
 	if instance.Spec.BootstrapperURL == "" {
-		instance.Status.BootstrapperURL = avaGoPrefix + instance.Spec.DeploymentName + "-0-service"
-		if network.Genesis != "" {
-			instance.Status.Genesis = network.Genesis
-		}
+		instance.Status.BootstrapperURL = bootstrapperAddresses(instance)
 	} else {
 		instance.Status.BootstrapperURL = instance.Spec.BootstrapperURL
-		instance.Status.Genesis = instance.Spec.Genesis
+	}
+
+	// The genesis is mounted into the nodes from a single ConfigMap, referenced from status
+	genesis := genesisOf(instance, network)
+	if err := r.ensureGenesis(ctx, req, instance, genesis, l); err != nil {
+		return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonSecretsFailed, err, l)
 	}
 
 	if err := r.Status().Update(ctx, instance); err != nil {
 		l.Error(err, "Failed to update instance status")
 	}
 
-	if err := r.ensureConfigMap(
-		ctx,
-		req,
-		instance,
-		r.avagoConfigMap(instance, avaGoPrefix+instance.Spec.DeploymentName+"init-script", common.AvagoBootstraperFinderScript),
-		l,
-	); err != nil {
+	if err := r.ensureService(ctx, req, instance, r.avagoAPIService(instance), l); err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if err := r.ensureBootstrapConfig(ctx, req, instance, l); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if cm := r.avagoChainConfigMap(instance); cm != nil {
+		if err := r.ensureConfigMap(ctx, req, instance, cm, l); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	for i := 0; i < instance.Spec.NodeCount; i++ {
 		switch {
-		case (instance.Spec.BootstrapperURL == "") && (network.Genesis != "") && len(instance.Spec.ExistingSecrets) == 0:
+		case isGeneratedNetwork(instance):
+			keyPair, err := r.ensureNetworkKeyPair(ctx, instance, &network, i, l)
+			if err != nil {
+				return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonSecretsFailed, err, l)
+			}
 			if err := r.ensureSecret(
 				ctx,
 				req,
 				instance,
 				r.avagoSecret(
 					instance,
-					getSecretBaseName(*instance, i),
-					network.KeyPairs[i].Cert,
-					network.KeyPairs[i].Key,
-					network.Genesis,
+					nodeBaseName(instance, i),
+					keyPair.Cert,
+					keyPair.Key,
 				),
 				l,
 			); err != nil {
-				return ctrl.Result{}, err
+				return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonSecretsFailed, err, l)
 			}
 		case (instance.Spec.Genesis != "") && (len(instance.Spec.Certificates) > 0) && len(instance.Spec.ExistingSecrets) == 0:
 			bytes, err := base64.StdEncoding.DecodeString(instance.Spec.Certificates[i].Cert)
 			if err != nil {
-				instance.Status.Error = err.Error()
-				if err := r.Status().Update(ctx, instance); err != nil {
-					l.Error(err, "error calling Update")
-				}
-				return ctrl.Result{}, err
+				return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonSecretsFailed, err, l)
 			}
 			tempCert := string(bytes)
 			bytes, err = base64.StdEncoding.DecodeString(instance.Spec.Certificates[i].Key)
 			if err != nil {
-				instance.Status.Error = err.Error()
-				if err := r.Status().Update(ctx, instance); err != nil {
-					l.Error(err, "error calling Update")
-				}
-				return ctrl.Result{}, err
+				return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonSecretsFailed, err, l)
 			}
 			tempKey := string(bytes)
 			// Will not create or update any if len(instance.Spec.Secrets) > 0
@@ -216,14 +218,13 @@ func (r *AvalanchegoReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				instance,
 				r.avagoSecret(
 					instance,
-					getSecretBaseName(*instance, i),
+					nodeBaseName(instance, i),
 					tempCert,
 					tempKey,
-					instance.Spec.Genesis,
 				),
 				l,
 			); err != nil {
-				return ctrl.Result{}, err
+				return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonSecretsFailed, err, l)
 			}
 		default:
 			if len(instance.Spec.ExistingSecrets) == 0 {
@@ -233,86 +234,161 @@ func (r *AvalanchegoReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 					instance,
 					r.avagoSecret(
 						instance,
-						getSecretBaseName(*instance, i),
+						nodeBaseName(instance, i),
 						"",
 						"",
-						instance.Spec.Genesis,
 					), l,
 				); err != nil {
-					return ctrl.Result{}, err
+					return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonSecretsFailed, err, l)
 				}
 			}
 		}
 	}
 
+	r.reportNetworkCheck(instance, problems)
+
+	// Starting scheduled backups before the StatefulSets are ensured, a backup may stop its node
+	backupRequeue, err := r.reconcileBackup(ctx, instance, l)
+	if err != nil {
+		return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonBackupFailed, err, l)
+	}
+
+	// A new image is rolled out following spec.upgradeStrategy, instead of the usual order
+	upgrade, upgradeRequeue, err := r.reconcileUpgrade(ctx, instance, l)
+	if err != nil {
+		return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonUpgradeFailed, err, l)
+	}
+
 	// Running ensureStatefulSet in a separate loop
 	// Otherwise ensureSecret will create secret with an empty certificate
+	//
+	// Genesis stakers of a generated network are all created at once, they find each other through the bootstrapper.
+	// Otherwise nodes are created and updated one at a time, in order of their index:
+	// once a node is not ready, the nodes after it wait until it is.
+	canUpdate := true
+	outdated := 0
 	for i := 0; i < instance.Spec.NodeCount; i++ {
-		serviceName := instance.Spec.DeploymentName + "-" + strconv.Itoa(i)
-		networkMemberUriName := avaGoPrefix + serviceName + "-service"
+		networkMemberUriName := avaGoPrefix + nodeBaseName(instance, i) + "-service"
 
 		if err := r.ensureService(
 			ctx,
 			req,
-			r.avagoService(instance, serviceName),
+			instance,
+			r.avagoService(instance, i),
 			l,
 		); err != nil {
 			return ctrl.Result{}, err
 		}
 
-		if err := r.ensurePVC(
-			ctx,
-			req,
-			r.avagoPVC(instance, instance.Spec.DeploymentName+"-"+strconv.Itoa(i)),
-			l,
-		); err != nil {
-			return ctrl.Result{}, err
+		// The database of ephemeral test networks is kept in an emptyDir instead
+		if !instance.Spec.Storage.EmptyDir {
+			if err := r.autoExpandVolume(ctx, instance, i, l); err != nil {
+				return ctrl.Result{}, err
+			}
+			if err := r.ensurePVC(
+				ctx,
+				req,
+				instance,
+				r.avagoPVC(instance, i),
+				l,
+			); err != nil {
+				return ctrl.Result{}, err
+			}
 		}
 
-		var async asyncCreateStatefulSet
-
-		// In case of brand new network - create all statefulSets asynchronously
-		if !reflect.ValueOf(network).IsZero() {
-			async = createStsAsync
-		} else {
-			async = createStsSync
+		canUpdateNode := canUpdate
+		if upgrade != nil {
+			canUpdateNode = upgrade.canUpdate(i)
 		}
-
-		if err := r.ensureStatefulSet(
+		upToDate, ready, err := r.ensureStatefulSet(
 			ctx,
 			req,
 			instance,
-			r.avagoStatefulSet(instance, instance.Spec.DeploymentName+"-"+strconv.Itoa(i), i),
+			r.avagoStatefulSet(instance, i),
 			l,
-			async,
-		); err != nil {
-			instance.Status.Error = err.Error()
-			if err := r.Status().Update(ctx, instance); err != nil {
-				l.Error(err, "error calling ensureStatefulSet error status update")
-			}
-			return ctrl.Result{}, err
-		} else if notContainsS(instance.Status.NetworkMembersURI, networkMemberUriName) {
+			canUpdate || i < len(genesisStakers),
+			// Stopping a node for a backup doesn't wait for the nodes before it
+			canUpdateNode || isStoppedForBackup(instance, i),
+		)
+		if err != nil {
+			return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonStatefulSetFailed, err, l)
+		}
+		if notContainsS(instance.Status.NetworkMembersURI, networkMemberUriName) {
 			instance.Status.NetworkMembersURI = append(instance.Status.NetworkMembersURI, networkMemberUriName)
 			if err := r.Status().Update(ctx, instance); err != nil {
 				l.Error(err, "error calling NetworkMembersURI status update")
 			}
 		}
+		// Nodes held back by a paused upgrade are outdated, although their StatefulSet is applied
+		if !upToDate || isHeldBack(instance, i) {
+			outdated++
+		}
+		if !upToDate || !ready {
+			canUpdate = false
+		}
+	}
+
+	// Removing the nodes left over after nodeCount was lowered
+	removing, err := r.removeNodes(ctx, instance, l)
+	if err != nil {
+		return ctrl.Result{}, r.setErrorStatus(ctx, instance, reasonScaleDownFailed, err, l)
 	}
+
 	// Assuming that all the above operations are now finished successfully, clearing the error status
-	instance.Status.Error = ""
-	if err := r.Status().Update(ctx, instance); err != nil {
-		l.Error(err, "error cleating error status update")
+	allReady, err := r.setReadyStatus(ctx, instance, genesis, outdated, removing, l)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if allReady {
+		if err := r.removeStaleGenesis(ctx, instance, l); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.removeStaleChainConfig(ctx, instance, l); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	requeueAfter := []time.Duration{backupRequeue, upgradeRequeue}
+	if !allReady {
+		// StatefulSet updates trigger a new reconciliation as well, requeueing in case a pod becomes ready unnoticed
+		requeueAfter = append(requeueAfter, readinessBackoff(instance))
+	}
+	if instance.Spec.Storage.AutoExpand != nil && !instance.Spec.Storage.EmptyDir {
+		// Disk usage changes don't trigger a reconciliation
+		requeueAfter = append(requeueAfter, diskUsageInterval)
 	}
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: shortestRequeue(requeueAfter...)}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AvalanchegoReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&chainv1alpha1.Avalanchego{}).
+		// Status updates don't trigger a reconciliation, the nodes are probed again after RequeueAfter
+		For(&chainv1alpha1.Avalanchego{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			// The resume-upgrade annotation doesn't change the generation
+			predicate.AnnotationChangedPredicate{},
+		))).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.Secret{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&corev1.ConfigMap{}).
+		// The bootstrap config follows the IP of the bootstrapper pod
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(r.podToAvalanchego)).
 		Complete(r)
 }
 
+// shortestRequeue returns the shortest of the given durations, ignoring zero ones which mean no requeue
+func shortestRequeue(durations ...time.Duration) time.Duration {
+	var shortest time.Duration
+	for _, d := range durations {
+		if d > 0 && (shortest == 0 || d < shortest) {
+			shortest = d
+		}
+	}
+	return shortest
+}
+
 func notContainsS(s []string, str string) bool {
 	for _, v := range s {
 		if v == str {
@@ -322,6 +398,26 @@ func notContainsS(s []string, str string) bool {
 	return true
 }
 
-func getSecretBaseName(instance chainv1alpha1.Avalanchego, nodeId int) string {
+// removeS returns s without the occurrences of str
+func removeS(s []string, str string) []string {
+	res := make([]string, 0, len(s))
+	for _, v := range s {
+		if v != str {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// nodeBaseName returns the name of a node, the objects of the node are named after it
+func nodeBaseName(instance *chainv1alpha1.Avalanchego, nodeId int) string {
 	return instance.Spec.DeploymentName + "-" + strconv.Itoa(nodeId)
 }
+
+// getSecretName returns the name of the Secret with staking certificate and key of the node
+func getSecretName(instance chainv1alpha1.Avalanchego, nodeId int) string {
+	if len(instance.Spec.ExistingSecrets) > 0 {
+		return instance.Spec.ExistingSecrets[nodeId]
+	}
+	return avaGoPrefix + nodeBaseName(&instance, nodeId) + "-key"
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+)
+
+const (
+	eventBackupStarted   = "BackupStarted"
+	eventBackupCompleted = "BackupCompleted"
+	eventBackupFailed    = "BackupFailed"
+
+	// backupPollInterval is how often snapshots in progress are checked
+	backupPollInterval = 10 * time.Second
+)
+
+// reconcileBackup starts scheduled backups, follows their snapshots and deletes the ones past spec.backup.keep.
+// A backup in progress stops its node if spec.backup.stopNode is set, see isStoppedForBackup.
+// Returns when to reconcile again, for the next scheduled backup or to follow the snapshots in progress.
+func (r *AvalanchegoReconciler) reconcileBackup(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) (time.Duration, error) {
+	backup := instance.Spec.Backup
+	if backup == nil || instance.Spec.Storage.EmptyDir {
+		return 0, nil
+	}
+	schedule, err := chainv1alpha1.ParseCronSchedule(backup.Schedule)
+	if err != nil {
+		return 0, fmt.Errorf("invalid backup schedule: %w", err)
+	}
+
+	changed := false
+	for i := range instance.Status.Backups {
+		updated, err := r.observeBackup(ctx, instance, &instance.Status.Backups[i], l)
+		if err != nil {
+			return 0, err
+		}
+		changed = changed || updated
+	}
+
+	now := r.Clock.Now().UTC()
+	last := instance.CreationTimestamp.Time
+	if instance.Status.LastBackupTime != nil {
+		last = instance.Status.LastBackupTime.Time
+	}
+	next := schedule.Next(last.UTC())
+	// Missed schedules are not caught up on, only one backup is taken
+	if currentBackup(instance) == nil && !next.IsZero() && !now.Before(next) {
+		name := avaGoPrefix + nodeBaseName(instance, backup.Node) + "-pvc-" + now.Format("20060102-150405")
+		l.Info("Starting backup", "node", backup.Node, "VolumeSnapshot", name)
+		instance.Status.Backups = append(instance.Status.Backups, chainv1alpha1.Backup{
+			Name:      name,
+			Node:      backup.Node,
+			StartTime: metav1.NewTime(now),
+		})
+		instance.Status.LastBackupTime = &metav1.Time{Time: now}
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventBackupStarted, "Backing up node %d to %s", backup.Node, name)
+		next = schedule.Next(now)
+		changed = true
+	}
+
+	if current := currentBackup(instance); current != nil {
+		updated, err := r.takeBackupSnapshot(ctx, instance, current, l)
+		if err != nil {
+			return 0, err
+		}
+		changed = changed || updated
+	}
+
+	pruned, err := r.pruneBackups(ctx, instance, l)
+	if err != nil {
+		return 0, err
+	}
+	if changed || pruned {
+		if err := r.Status().Update(ctx, instance); err != nil {
+			l.Error(err, "error calling Backups status update")
+			return 0, err
+		}
+	}
+
+	for _, b := range instance.Status.Backups {
+		if !b.ReadyToUse && b.Error == "" {
+			return backupPollInterval, nil
+		}
+	}
+	if next.IsZero() {
+		return 0, nil
+	}
+	return next.Sub(now), nil
+}
+
+// currentBackup returns the backup whose snapshot is not taken yet, or nil if there is none
+func currentBackup(instance *chainv1alpha1.Avalanchego) *chainv1alpha1.Backup {
+	for i := range instance.Status.Backups {
+		b := &instance.Status.Backups[i]
+		if b.SnapshotTime == nil && b.Error == "" {
+			return b
+		}
+	}
+	return nil
+}
+
+// isStoppedForBackup returns true if the node has to be stopped, while its snapshot is being taken
+func isStoppedForBackup(instance *chainv1alpha1.Avalanchego, nodeId int) bool {
+	if instance.Spec.Backup == nil || !instance.Spec.Backup.StopNode {
+		return false
+	}
+	current := currentBackup(instance)
+	return current != nil && current.Node == nodeId
+}
+
+// takeBackupSnapshot creates the VolumeSnapshot of the current backup, once its node is stopped if needed.
+// The backup is no longer current once the snapshot is taken, which restarts the node.
+// Returns true if the backup status changed.
+func (r *AvalanchegoReconciler) takeBackupSnapshot(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	current *chainv1alpha1.Backup,
+	l logr.Logger,
+) (bool, error) {
+	name := avaGoPrefix + nodeBaseName(instance, current.Node)
+	if isStoppedForBackup(instance, current.Node) {
+		err := r.Get(ctx, types.NamespacedName{Name: name + "-0", Namespace: instance.Namespace}, &corev1.Pod{})
+		if err == nil {
+			l.Info("Waiting for node to stop before the backup", "node", current.Node)
+			return false, nil
+		} else if !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: current.Name, Namespace: instance.Namespace}, snapshot)
+	if err == nil {
+		return r.observeBackup(ctx, instance, current, l)
+	} else if meta.IsNoMatchError(err) {
+		// Giving up on this backup, so a stopped node isn't kept down
+		current.Error = "VolumeSnapshots are not supported by the cluster, the snapshot CRDs are not installed"
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventBackupFailed, "Backup %s failed: %s", current.Name, current.Error)
+		return true, nil
+	} else if !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	err = r.Get(ctx, types.NamespacedName{Name: name + "-pvc", Namespace: instance.Namespace}, pvc)
+	if errors.IsNotFound(err) {
+		current.Error = "PVC " + name + "-pvc not found"
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventBackupFailed, "Backup %s failed: %s", current.Name, current.Error)
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	// Backups are not owned by the instance, they outlive it
+	l.Info("Taking snapshot", "PVC", pvc.Name, "VolumeSnapshot", current.Name)
+	if err := r.Create(ctx, newVolumeSnapshot(current.Name, pvc, instance.Spec.Backup.VolumeSnapshotClassName)); err != nil {
+		// Giving up on this backup as well
+		current.Error = fmt.Sprintf("couldn't create VolumeSnapshot: %s", err)
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventBackupFailed, "Backup %s failed: %s", current.Name, current.Error)
+		return true, nil
+	}
+	return false, nil
+}
+
+// observeBackup copies the status of the VolumeSnapshot of a backup in progress to the backup.
+// Returns true if the backup status changed.
+func (r *AvalanchegoReconciler) observeBackup(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	b *chainv1alpha1.Backup,
+	l logr.Logger,
+) (bool, error) {
+	if b.ReadyToUse || b.Error != "" {
+		return false, nil
+	}
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: b.Name, Namespace: instance.Namespace}, snapshot)
+	if errors.IsNotFound(err) {
+		if b.SnapshotTime == nil {
+			// Not created yet, see takeBackupSnapshot
+			return false, nil
+		}
+		b.Error = "VolumeSnapshot was deleted"
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventBackupFailed, "Backup %s failed: %s", b.Name, b.Error)
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	status := volumeSnapshotStatus(snapshot)
+	changed := false
+	if status.Error != "" {
+		b.Error = status.Error
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventBackupFailed, "Backup %s failed: %s", b.Name, b.Error)
+		return true, nil
+	}
+	if b.SnapshotTime == nil && status.CreationTime != nil {
+		l.Info("Snapshot taken", "VolumeSnapshot", b.Name)
+		b.SnapshotTime = status.CreationTime
+		changed = true
+	}
+	if status.ReadyToUse {
+		b.ReadyToUse = true
+		b.Size = status.RestoreSize
+		size := "unknown size"
+		if b.Size != nil {
+			size = b.Size.String()
+		}
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventBackupCompleted, "Backup %s of node %d is ready to use, %s", b.Name, b.Node, size)
+		changed = true
+	}
+	return changed, nil
+}
+
+// pruneBackups deletes the oldest backups ready to use, keeping spec.backup.keep of them.
+// Failed backups don't count, so failing runs never delete good snapshots: only the latest failure is kept
+// in the status, the older ones are dropped along with whatever their VolumeSnapshot holds.
+// Returns true if backups were deleted.
+func (r *AvalanchegoReconciler) pruneBackups(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) (bool, error) {
+	keep := instance.Spec.Backup.Keep
+	if keep <= 0 {
+		keep = chainv1alpha1.DefaultBackupKeep
+	}
+	ready, lastFailed := 0, -1
+	for i, b := range instance.Status.Backups {
+		if b.ReadyToUse {
+			ready++
+		} else if b.Error != "" {
+			lastFailed = i
+		}
+	}
+	backups := make([]chainv1alpha1.Backup, 0, len(instance.Status.Backups))
+	pruned := false
+	for i, b := range instance.Status.Backups {
+		switch {
+		case b.ReadyToUse && ready > keep:
+			l.Info("Deleting old backup", "VolumeSnapshot", b.Name)
+			ready--
+		case !b.ReadyToUse && b.Error != "" && i != lastFailed:
+			l.Info("Deleting failed backup", "VolumeSnapshot", b.Name)
+		default:
+			backups = append(backups, b)
+			continue
+		}
+		snapshot := &unstructured.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		snapshot.SetName(b.Name)
+		snapshot.SetNamespace(instance.Namespace)
+		if err := r.Delete(ctx, snapshot); err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			instance.Status.Backups = append(backups, instance.Status.Backups[i:]...)
+			return pruned, err
+		}
+		pruned = true
+	}
+	instance.Status.Backups = backups
+	return pruned, nil
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
+)
+
+// bootstrapConfigKey is the name of the config file of the nodes, mounted from the bootstrap ConfigMap
+const bootstrapConfigKey = "conf.json"
+
+func bootstrapConfigMapName(instance *chainv1alpha1.Avalanchego) string {
+	return avaGoPrefix + instance.Spec.DeploymentName + "-bootstrap"
+}
+
+// bootstrapConfigKeyOf returns the key of the config of a node in the bootstrap ConfigMap.
+// Bootstrappers have their own, listing the other bootstrappers only.
+func bootstrapConfigKeyOf(instance *chainv1alpha1.Avalanchego, nodeId int) string {
+	if nodeRole(instance, nodeId) == chainv1alpha1.NodeRoleBootstrapper {
+		return "conf-" + strconv.Itoa(nodeId) + ".json"
+	}
+	return bootstrapConfigKey
+}
+
+// bootstrappers returns the beacons of the deployment, node 0 unless given.
+// The beacons behind bootstrapperURL are not listed, they are only known once it is resolved,
+// nor are the public beacons of mainnet and fuji.
+func bootstrappers(instance *chainv1alpha1.Avalanchego) []chainv1alpha1.BootstrapperSpec {
+	if len(instance.Spec.Bootstrappers) > 0 {
+		return instance.Spec.Bootstrappers
+	}
+	if instance.Spec.BootstrapperURL != "" || instance.Spec.JoinsPublicNetwork() {
+		return nil
+	}
+	return []chainv1alpha1.BootstrapperSpec{{Node: &[]int{0}[0]}}
+}
+
+// bootstrapperNodes returns the indices of the nodes of this deployment which are bootstrappers
+func bootstrapperNodes(instance *chainv1alpha1.Avalanchego) []int {
+	var nodes []int
+	for _, b := range bootstrappers(instance) {
+		if b.Node != nil {
+			nodes = append(nodes, *b.Node)
+		}
+	}
+	return nodes
+}
+
+// nodeRole returns the role spec.bootstrappers gives to a node
+func nodeRole(instance *chainv1alpha1.Avalanchego, nodeId int) chainv1alpha1.NodeRole {
+	for _, node := range bootstrapperNodes(instance) {
+		if node == nodeId {
+			return chainv1alpha1.NodeRoleBootstrapper
+		}
+	}
+	return chainv1alpha1.NodeRoleNode
+}
+
+// usesBootstrapConfig returns true if the node bootstraps from other nodes,
+// false for the only bootstrapper of a new network, and for nodes joining mainnet or fuji
+func usesBootstrapConfig(instance *chainv1alpha1.Avalanchego, nodeId int) bool {
+	if instance.Spec.JoinsPublicNetwork() {
+		return false
+	}
+	return nodeRole(instance, nodeId) != chainv1alpha1.NodeRoleBootstrapper || len(bootstrappers(instance)) > 1
+}
+
+// bootstrapperAddresses returns the service names of the bootstrappers of this deployment and the addresses of the
+// external ones, separated by commas
+func bootstrapperAddresses(instance *chainv1alpha1.Avalanchego) string {
+	var addresses []string
+	for _, b := range bootstrappers(instance) {
+		if b.Node != nil {
+			addresses = append(addresses, avaGoPrefix+nodeBaseName(instance, *b.Node)+"-service")
+		} else {
+			addresses = append(addresses, b.Address)
+		}
+	}
+	return strings.Join(addresses, ",")
+}
+
+// quorum is the number of beacons out of n a node waits for before it starts
+func quorum(n int) int {
+	return n/2 + 1
+}
+
+// beacon is a bootstrapper of the spec, and the nodes found behind it, if any
+type beacon struct {
+	// Index of the node of this deployment, -1 for external ones
+	node  int
+	found []common.Bootstrapper
+}
+
+// ensureBootstrapConfig writes the IPs and NodeIDs of the bootstrappers into the bootstrap ConfigMap.
+// The other nodes wait to start until a quorum of the bootstrappers is known: until then their config is missing.
+// Bootstrappers don't wait, they start with the other bootstrappers known so far.
+// Once written, a config is only replaced with one listing a quorum of bootstrappers, e.g. after one is rescheduled.
+func (r *AvalanchegoReconciler) ensureBootstrapConfig(
+	ctx context.Context,
+	req ctrl.Request,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) error {
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bootstrapConfigMapName(instance),
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				"app":           bootstrapConfigMapName(instance),
+				deploymentLabel: instance.Spec.DeploymentName,
+			},
+		},
+		Data: map[string]string{},
+	}
+	_ = controllerutil.SetControllerReference(instance, cm, r.Scheme) // TODO should we return this error if non-nil?
+
+	found := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: cm.Name, Namespace: cm.Namespace}, found)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	beacons, err := r.findBootstrappers(ctx, instance)
+	if err != nil {
+		return err
+	}
+	setConfig := func(key string, except int) error {
+		var (
+			known, expected int
+			peers           []common.Bootstrapper
+		)
+		for _, b := range beacons {
+			if b.node >= 0 && b.node == except {
+				continue
+			}
+			expected++
+			if len(b.found) > 0 {
+				known++
+				peers = append(peers, b.found...)
+			}
+		}
+		previous, written := found.Data[key]
+		if written {
+			cm.Data[key] = previous
+		}
+		if known < quorum(expected) && (written || except < 0) {
+			return nil
+		}
+		config, err := json.Marshal(common.NewBootstrapConfig(peers))
+		if err != nil {
+			return err
+		}
+		if previous != string(config) {
+			l.Info("Bootstrappers changed", "key", key, "config", string(config))
+		}
+		cm.Data[key] = string(config)
+		return nil
+	}
+
+	if err := setConfig(bootstrapConfigKey, -1); err != nil {
+		return err
+	}
+	for _, node := range bootstrapperNodes(instance) {
+		if usesBootstrapConfig(instance, node) {
+			if err := setConfig(bootstrapConfigKeyOf(instance, node), node); err != nil {
+				return err
+			}
+		}
+	}
+	return r.ensureConfigMap(ctx, req, instance, cm, l)
+}
+
+// findBootstrappers looks for the nodes behind every bootstrapper.
+// A node of this deployment is found as soon as its pod has an IP: it only becomes ready once other nodes connected
+// to it. An external node is found once its host is resolved, bootstrapperURL once every node behind it answered
+// info.getNodeID.
+func (r *AvalanchegoReconciler) findBootstrappers(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+) ([]beacon, error) {
+	l := ctrl.LoggerFrom(ctx)
+	resolver := &common.BootstrapResolver{NodeAPI: r.NodeAPI, HTTPPort: 9650, StakingPort: 9651}
+	if instance.Spec.BootstrapperURL != "" {
+		b := beacon{node: -1}
+		if r.NodeAPI == nil {
+			return []beacon{b}, nil
+		}
+		found, err := resolver.Resolve(ctx, instance.Spec.BootstrapperURL)
+		if err != nil {
+			l.Info("Bootstrapper not available yet", "bootstrapper", instance.Spec.BootstrapperURL, "error", err.Error())
+		}
+		b.found = found
+		return []beacon{b}, nil
+	}
+
+	var beacons []beacon
+	for _, spec := range bootstrappers(instance) {
+		if spec.Node == nil {
+			b := beacon{node: -1}
+			host, port, _ := net.SplitHostPort(spec.Address)
+			stakingPort, _ := strconv.Atoi(port)
+			if ips, err := resolver.LookupIPs(ctx, host); err != nil {
+				l.Info("Bootstrapper not available yet", "bootstrapper", spec.Address, "error", err.Error())
+			} else {
+				b.found = []common.Bootstrapper{{IP: ips[0], Port: stakingPort, NodeID: spec.NodeID}}
+			}
+			beacons = append(beacons, b)
+			continue
+		}
+
+		b := beacon{node: *spec.Node}
+		found, err := r.findBootstrapperNode(ctx, instance, *spec.Node)
+		if err != nil {
+			return nil, err
+		}
+		if found != nil {
+			b.found = []common.Bootstrapper{*found}
+		}
+		beacons = append(beacons, b)
+	}
+	return beacons, nil
+}
+
+// findBootstrapperNode returns the IP and NodeID of a node of this deployment, nil if its pod has no IP yet
+func (r *AvalanchegoReconciler) findBootstrapperNode(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	nodeId int,
+) (*common.Bootstrapper, error) {
+	pod := &corev1.Pod{}
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      avaGoPrefix + nodeBaseName(instance, nodeId) + "-0",
+		Namespace: instance.Namespace,
+	}, pod)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if pod.Status.PodIP == "" || pod.Status.Phase != corev1.PodRunning || !pod.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+
+	cert, err := r.nodeCertificate(ctx, instance, nodeId)
+	if err != nil {
+		return nil, err
+	}
+	var nodeID string
+	if cert != "" {
+		if nodeID, err = common.NodeIDFromCert(cert); err != nil {
+			return nil, err
+		}
+	} else if r.NodeAPI != nil {
+		// Without a certificate, only the node itself knows its NodeID
+		if nodeID, err = r.NodeAPI.NodeID(ctx, "http://"+pod.Status.PodIP+":9650"); err != nil {
+			ctrl.LoggerFrom(ctx).Info("Couldn't read the NodeID of the bootstrapper", "node", nodeId, "error", err.Error())
+			return nil, nil
+		}
+	}
+	if nodeID == "" {
+		return nil, nil
+	}
+	return &common.Bootstrapper{IP: pod.Status.PodIP, Port: 9651, NodeID: nodeID}, nil
+}
+
+// podToAvalanchego maps a node pod to the Avalanchego object it belongs to, so the bootstrap config follows the
+// bootstrapper as it is rescheduled
+func (r *AvalanchegoReconciler) podToAvalanchego(obj client.Object) []reconcile.Request {
+	deploymentName, ok := obj.GetLabels()[deploymentLabel]
+	if !ok {
+		return nil
+	}
+	list := &chainv1alpha1.AvalanchegoList{}
+	if err := r.List(context.Background(), list, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, instance := range list.Items {
+		if instance.Spec.DeploymentName == deploymentName {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      instance.Name,
+				Namespace: instance.Namespace,
+			}})
+		}
+	}
+	return requests
+}
@@ -18,36 +18,39 @@ package controllers
 
 import (
 	"context"
-	"fmt"
 	"reflect"
-	"sync"
-	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	kubeinformers "k8s.io/client-go/informers"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/cache"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
 	"github.com/go-logr/logr"
 )
 
 const (
-	isUpdateable            = true
-	isNotUpdateable         = false
-	stsUpdateTimeoutSeconds = 30
-)
+	isUpdateable    = true
+	isNotUpdateable = false
+
+	// specHashAnnotation holds the hash of the spec the operator last applied to a StatefulSet
+	specHashAnnotation = "chain.djtx.network/spec-hash"
 
-type asyncCreateStatefulSet bool
+	// unexpandableSizeAnnotation holds the size a PVC couldn't be expanded to, so the warning is only emitted once per size
+	unexpandableSizeAnnotation = "chain.djtx.network/unexpandable-size"
 
-var (
-	eventsWatcherClientSet *kubernetes.Clientset
-	isTestRun              bool = false
+	// eventDriftCorrected is the reason of events about owned objects restored by the operator
+	eventDriftCorrected = "DriftCorrected"
+
+	eventStorageExpanded      = "StorageExpanded"
+	eventStorageNotExpandable = "StorageNotExpandable"
 )
 
 func (r *AvalanchegoReconciler) ensureConfigMap(
@@ -57,10 +60,32 @@ func (r *AvalanchegoReconciler) ensureConfigMap(
 	s *corev1.ConfigMap,
 	l logr.Logger,
 ) error {
-	_, err := upsertObject(ctx, r, s, isUpdateable, l)
+	result, err := upsertObject(ctx, r, s, isUpdateable, l)
+	r.recordDrift(instance, s, result)
 	return err
 }
 
+// removeStaleChainConfig deletes the chain config ConfigMap of the instance once spec.cChain.config is removed.
+// Only called once all the nodes are up to date, none of them mounts it anymore.
+func (r *AvalanchegoReconciler) removeStaleChainConfig(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) error {
+	if cChainConfig(instance) != "" {
+		return nil
+	}
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: chainConfigMapName(instance), Namespace: instance.Namespace}, cm)
+	if errors.IsNotFound(err) || (err == nil && !metav1.IsControlledBy(cm, instance)) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	l.Info("Deleting stale chain config", "configMap", cm.Name)
+	return client.IgnoreNotFound(r.Delete(ctx, cm))
+}
+
 func (r *AvalanchegoReconciler) ensureSecret(
 	ctx context.Context,
 	req ctrl.Request,
@@ -74,29 +99,42 @@ func (r *AvalanchegoReconciler) ensureSecret(
 	if instance.Spec.Genesis != "" && len(instance.Spec.Certificates) != 0 && len(instance.Spec.ExistingSecrets) == 0 {
 		isSecretUpdateable = isUpdateable
 	}
-	_, err := upsertObject(ctx, r, s, isSecretUpdateable, l)
+	// Secrets of generated networks are copies of the network Secret
+	if isGeneratedNetwork(instance) {
+		isSecretUpdateable = isUpdateable
+	}
+	if isSecretUpdateable {
+		if err := r.keepSecretGenesis(ctx, s); err != nil {
+			return err
+		}
+	}
+	result, err := upsertObject(ctx, r, s, isSecretUpdateable, l)
+	r.recordDrift(instance, s, result)
 	return err
 }
 
 func (r *AvalanchegoReconciler) ensureService(
 	ctx context.Context,
 	req ctrl.Request,
+	instance *chainv1alpha1.Avalanchego,
 	s *corev1.Service,
 	l logr.Logger,
 ) error {
 
-	_, err := upsertObject(ctx, r, s, isUpdateable, l)
+	result, err := upsertObject(ctx, r, s, isUpdateable, l)
+	r.recordDrift(instance, s, result)
 	return err
 }
 
 func (r *AvalanchegoReconciler) ensurePVC(
 	ctx context.Context,
 	req ctrl.Request,
+	instance *chainv1alpha1.Avalanchego,
 	s *corev1.PersistentVolumeClaim,
 	l logr.Logger,
 ) error {
 	// PVC is special in terms of update operation
-	// In order to update it, we need to set Spec.VolumeName and Spec.StorageClassName from existing state
+	// Only the requested size can be changed, the rest of the spec is taken from the existing state
 
 	// Searching for existent PVC first
 	found := &corev1.PersistentVolumeClaim{}
@@ -106,139 +144,146 @@ func (r *AvalanchegoReconciler) ensurePVC(
 	}, found)
 
 	if err == nil {
-		// Setting up Spec.VolumeName and Spec.StorageClassName values from existent PVC
+		// Setting up immutable Spec values from existent PVC
 		s.Spec.VolumeName = found.Spec.VolumeName
 		s.Spec.StorageClassName = found.Spec.StorageClassName
+		s.Spec.AccessModes = found.Spec.AccessModes
+		s.Spec.VolumeMode = found.Spec.VolumeMode
+		s.Spec.DataSource = found.Spec.DataSource
+		// Keeping the binding annotations and the protection finalizer
+		s.Annotations = mergeMaps(found.Annotations, s.Annotations)
+		s.Finalizers = found.Finalizers
+
+		size, err := r.pvcSize(ctx, instance, s, found, l)
+		if err != nil {
+			return err
+		}
+		s.Spec.Resources.Requests[corev1.ResourceStorage] = size
 	} else if !errors.IsNotFound(err) {
 		l.Error(err, "Failed to get existing PVC", s.GetNamespace(), "Type:", s.GetObjectKind().GroupVersionKind().String(), "Name:", s.GetName())
 		return err
 	}
-	_, err = upsertObject(ctx, r, s, isUpdateable, l)
+	result, err := upsertObject(ctx, r, s, isUpdateable, l)
+	r.recordDrift(instance, s, result)
 	return err
 }
 
-func (r *AvalanchegoReconciler) ensureStatefulSet(
+// pvcSize returns the size to request for an existing PVC.
+// A larger size is only requested if the storage class allows volume expansion, PVCs never shrink.
+func (r *AvalanchegoReconciler) pvcSize(
 	ctx context.Context,
-	req ctrl.Request,
 	instance *chainv1alpha1.Avalanchego,
-	s *appsv1.StatefulSet,
+	s *corev1.PersistentVolumeClaim,
+	found *corev1.PersistentVolumeClaim,
 	l logr.Logger,
-	async asyncCreateStatefulSet,
-) error {
+) (resource.Quantity, error) {
+	desired := s.Spec.Resources.Requests[corev1.ResourceStorage]
+	current := found.Spec.Resources.Requests[corev1.ResourceStorage]
+	if desired.Cmp(current) <= 0 {
+		return current, nil
+	}
 
-	err := waitForStatefulset(ctx, req, instance, s, l, r, async)
-	if err != nil {
-		return err
-	} else {
-		return nil
+	className := ""
+	if found.Spec.StorageClassName != nil {
+		className = *found.Spec.StorageClassName
 	}
+	expandable := false
+	if className != "" {
+		class := &storagev1.StorageClass{}
+		err := r.Get(ctx, types.NamespacedName{Name: className}, class)
+		if err != nil && !errors.IsNotFound(err) {
+			return current, err
+		}
+		expandable = err == nil && class.AllowVolumeExpansion != nil && *class.AllowVolumeExpansion
+	}
+	if !expandable {
+		if found.Annotations[unexpandableSizeAnnotation] != desired.String() {
+			r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventStorageNotExpandable,
+				"Can't expand PVC %s to %s, storage class %q doesn't allow volume expansion", s.Name, desired.String(), className)
+		}
+		s.Annotations = mergeMaps(s.Annotations, map[string]string{unexpandableSizeAnnotation: desired.String()})
+		return current, nil
+	}
+	delete(s.Annotations, unexpandableSizeAnnotation)
+	l.Info("Expanding PVC", "PVC", s.Name, "from", current.String(), "to", desired.String())
+	r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventStorageExpanded, "Expanding PVC %s from %s to %s", s.Name, current.String(), desired.String())
+	return desired, nil
 }
 
-func waitForStatefulset(
+// ensureStatefulSet creates the StatefulSet of a node, or updates it if its spec has changed.
+// A missing StatefulSet is only created if canCreate is true, an outdated one is only updated if canUpdate is true.
+// Returns whether the StatefulSet has the desired spec, and whether its pod is ready.
+func (r *AvalanchegoReconciler) ensureStatefulSet(
 	ctx context.Context,
 	req ctrl.Request,
 	instance *chainv1alpha1.Avalanchego,
 	s *appsv1.StatefulSet,
 	l logr.Logger,
-	r *AvalanchegoReconciler,
-	async asyncCreateStatefulSet) error {
+	canCreate bool,
+	canUpdate bool,
+) (upToDate bool, ready bool, err error) {
+	found := &appsv1.StatefulSet{}
+	err = r.Get(ctx, types.NamespacedName{
+		Name:      s.GetName(),
+		Namespace: s.GetNamespace(),
+	}, found)
 
-	// creates the clientset
-	if eventsWatcherClientSet == nil {
-		var err error
-		eventsWatcherClientSet, err = kubernetes.NewForConfig(ctrl.GetConfigOrDie())
-		if err != nil {
-			panic(err.Error())
+	switch {
+	case errors.IsNotFound(err) && !canCreate:
+		l.Info("Postponing StatefulSet creation until previous nodes are ready", "Namespace:", s.GetNamespace(), "Name:", s.GetName())
+		return false, false, nil
+	case errors.IsNotFound(err):
+		result, err := upsertObject(ctx, r, s, isUpdateable, l)
+		// Nodes are created one by one, a missing StatefulSet is only drift once all of them have been observed
+		if instance.Status.CurrentNodes >= instance.Spec.NodeCount {
+			r.recordDrift(instance, s, result)
 		}
+		return err == nil, false, err
+	case err != nil:
+		l.Error(err, "Failed to get existing StatefulSet", "Namespace:", s.GetNamespace(), "Name:", s.GetName())
+		return false, false, err
 	}
 
-	stop := make(chan struct{})
-	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(eventsWatcherClientSet, time.Second*1)
-	statefulSetInformer := kubeInformerFactory.Apps().V1().StatefulSets().Informer()
-
-	var wg sync.WaitGroup
-	wg.Add(1)
-	defer close(stop)
-
-	updateFunc := func(oldObj, newObj interface{}) {
-		new := newObj.(*appsv1.StatefulSet)
-		old := oldObj.(*appsv1.StatefulSet)
-		if new.Name == s.Name {
-			l.Info("Waiting for StatefulSet to become ready: " +
-				new.Namespace +
-				"/" + new.Name +
-				" ReadyReplicas: " + fmt.Sprint(new.Status.ReadyReplicas) +
-				" UpdatedReplicas: " + fmt.Sprint(new.Status.UpdatedReplicas))
-
-			// We must do this since testEnv neither creates real pods or changes their status
-			if isTestRun {
-				if new.Status.CollisionCount == old.Status.CollisionCount &&
-					new.Status.UpdateRevision == new.Status.CurrentRevision &&
-					new.Status.ReadyReplicas == int32(0) &&
-					new.Status.UpdatedReplicas == int32(0) {
-					l.Info("Finished waiting for updated StatefulSet: " + new.Namespace + "/" + new.Name)
-					wg.Done()
-				}
-			} else {
-				// .Status.CollisionCount is important check, it's zero-value indicates that there is real update happening
-				if new.Status.CollisionCount == old.Status.CollisionCount &&
-					new.Status.UpdateRevision == new.Status.CurrentRevision &&
-					new.Status.ReadyReplicas == *s.Spec.Replicas &&
-					new.Status.UpdatedReplicas == *s.Spec.Replicas {
-					l.Info("Finished waiting for updated StatefulSet: " + new.Namespace + "/" + new.Name)
-					wg.Done()
-				}
-			}
+	if found.Annotations[specHashAnnotation] != s.Annotations[specHashAnnotation] {
+		if !canUpdate {
+			l.Info("Postponing StatefulSet update until previous nodes are ready", "Namespace:", s.GetNamespace(), "Name:", s.GetName())
+			return false, isStatefulSetReady(found), nil
 		}
+		_, err = upsertObject(ctx, r, s, isUpdateable, l)
+		// The pod is about to be replaced, it is ready once the StatefulSet controller reports so
+		return err == nil, false, err
 	}
 
-	if !async {
-		statefulSetInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-			UpdateFunc: updateFunc,
-		})
-		kubeInformerFactory.Start(stop)
-	} else {
-		// ensureStatefulSet was called in async mode
-		wg.Done()
-	}
-
-	_, err := upsertObject(ctx, r, s, isUpdateable, l)
-	if err != nil {
-		return err
-	}
-
-	if waitTimeout(&wg, (time.Second * time.Duration(stsUpdateTimeoutSeconds))) {
-		err = errors.NewTimeoutError("Timed out waiting for StatefulSet: "+s.Name+" to become ready", stsUpdateTimeoutSeconds)
-		return err
+	// The desired spec has not changed, making sure nobody modified the StatefulSet since it was applied
+	if !hasDrifted(s, found) {
+		return true, isStatefulSetReady(found), nil
 	}
-
-	return nil
+	result, err := upsertObject(ctx, r, s, isUpdateable, l)
+	r.recordDrift(instance, s, result)
+	return err == nil, false, err
 }
 
-// waitTimeout waits for the waitgroup for the specified max timeout.
-// Returns true if waiting timed out.
-func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
-	c := make(chan struct{})
-	go func() {
-		defer close(c)
-		wg.Wait()
-	}()
-	select {
-	case <-c:
-		return false // completed normally
-	case <-time.After(timeout):
-		return true // timed out
+// isStatefulSetReady returns true if the StatefulSet controller has rolled out the current spec and all replicas are ready
+func isStatefulSetReady(s *appsv1.StatefulSet) bool {
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
 	}
+	return s.Status.ObservedGeneration >= s.Generation &&
+		s.Status.CurrentRevision == s.Status.UpdateRevision &&
+		s.Status.ReadyReplicas == replicas &&
+		s.Status.UpdatedReplicas == replicas
 }
 
 // Creates or updates k8s object if it already exists.
 // isUpdateable must be true to allow update (some objects like PVC are immutable)
+// Existing objects are only updated if they differ from targetObj.
 func upsertObject(
 	ctx context.Context,
 	r *AvalanchegoReconciler,
 	targetObj client.Object,
 	isUpdateable bool,
-	l logr.Logger) (existed bool, err error) {
+	l logr.Logger) (result controllerutil.OperationResult, err error) {
 
 	commonLogLabels := []interface{}{"Namespace:", targetObj.GetNamespace(), "Type:", targetObj.GetObjectKind().GroupVersionKind().String(), "Name:", targetObj.GetName()}
 
@@ -250,24 +295,26 @@ func upsertObject(
 		Namespace: targetObj.GetNamespace(),
 	}, foundObj)
 
-	if err == nil && isUpdateable {
+	if err == nil && isUpdateable && !hasDrifted(targetObj, foundObj) {
+		return controllerutil.OperationResultNone, nil
+	} else if err == nil && isUpdateable {
 		l.Info("Updating existing object", commonLogLabels...)
 		// Some of k8s services require ResourceVersion to be specified within update
 		targetObj.SetResourceVersion(foundObj.GetResourceVersion())
 		if err := r.Update(ctx, targetObj); err != nil {
 			// Update failed
 			l.Error(err, "Failed to update object", commonLogLabels...)
-			return true, err
+			return controllerutil.OperationResultNone, err
 		} else {
 			l.Info("Updated existing object", commonLogLabels...)
-			return true, err
+			return controllerutil.OperationResultUpdated, err
 		}
 	} else if err == nil && !isUpdateable {
 		l.Info("Found existing object but it's not updatable", commonLogLabels...)
-		return true, err
+		return controllerutil.OperationResultNone, err
 	} else if !errors.IsNotFound(err) {
 		l.Error(err, "Failed to find existing object", commonLogLabels...)
-		return false, err
+		return controllerutil.OperationResultNone, err
 	}
 
 	// Create the Object
@@ -275,9 +322,57 @@ func upsertObject(
 	if err := r.Create(ctx, targetObj); err != nil {
 		// Creation failed
 		l.Error(err, "Failed to create new object", commonLogLabels...)
-		return true, err
+		return controllerutil.OperationResultNone, err
 	}
 	l.Info("Successfully created a new object", commonLogLabels...)
 	// Creation was successful
-	return true, nil
+	return controllerutil.OperationResultCreated, nil
+}
+
+// hasDrifted returns true if found lacks any of the labels, annotations or contents of desired.
+// Fields desired leaves empty are ignored, they are defaulted by the API server.
+func hasDrifted(desired client.Object, found client.Object) bool {
+	if !equality.Semantic.DeepDerivative(desired.GetLabels(), found.GetLabels()) ||
+		!equality.Semantic.DeepDerivative(desired.GetAnnotations(), found.GetAnnotations()) {
+		return true
+	}
+	switch d := desired.(type) {
+	case *appsv1.StatefulSet:
+		return !equality.Semantic.DeepDerivative(d.Spec, found.(*appsv1.StatefulSet).Spec)
+	case *corev1.Service:
+		return !equality.Semantic.DeepDerivative(d.Spec, found.(*corev1.Service).Spec)
+	case *corev1.PersistentVolumeClaim:
+		return !equality.Semantic.DeepDerivative(d.Spec, found.(*corev1.PersistentVolumeClaim).Spec)
+	case *corev1.ConfigMap:
+		return !equality.Semantic.DeepDerivative(d.Data, found.(*corev1.ConfigMap).Data)
+	case *corev1.Secret:
+		// StringData is write-only, the API server merges it into Data
+		foundData := found.(*corev1.Secret).Data
+		for k, v := range d.StringData {
+			if string(foundData[k]) != v {
+				return true
+			}
+		}
+		return !equality.Semantic.DeepDerivative(d.Data, foundData)
+	}
+	return false
+}
+
+// recordDrift emits an event if an object had to be restored, although the spec of the instance did not change.
+// Objects created or updated while rolling out a new spec are not reported.
+func (r *AvalanchegoReconciler) recordDrift(
+	instance *chainv1alpha1.Avalanchego,
+	obj client.Object,
+	result controllerutil.OperationResult,
+) {
+	if result == controllerutil.OperationResultNone || !isSettled(instance) {
+		return
+	}
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	switch result {
+	case controllerutil.OperationResultCreated:
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventDriftCorrected, "Recreated missing %s %s", kind, obj.GetName())
+	default:
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventDriftCorrected, "Restored %s %s, which was modified outside of the operator", kind, obj.GetName())
+	}
 }
@@ -0,0 +1,192 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
+)
+
+// The genesis of a network is stored once, in an immutable ConfigMap named after its hash,
+// instead of in the status and the Secret of every node
+const (
+	// genesisKey is the key of the genesis in the genesis ConfigMap
+	genesisKey = "genesis.json"
+
+	// genesisDir is where the genesis ConfigMap is mounted into the nodes
+	genesisDir = "/etc/avalanchego/genesis"
+
+	// genesisHashLength is the number of characters of the hash in the name of the genesis ConfigMap
+	genesisHashLength = 10
+)
+
+func genesisConfigMapPrefix(instance *chainv1alpha1.Avalanchego) string {
+	return avaGoPrefix + instance.Spec.DeploymentName + "-genesis"
+}
+
+func genesisHash(genesis string) string {
+	sum := sha256.Sum256([]byte(genesis))
+	return hex.EncodeToString(sum[:])
+}
+
+// genesisOf returns the genesis the operator has for the instance, empty if the nodes read it from existingSecrets
+// or join a public network
+func genesisOf(instance *chainv1alpha1.Avalanchego, network common.Network) string {
+	if isGeneratedNetwork(instance) {
+		return network.Genesis
+	}
+	return instance.Spec.Genesis
+}
+
+// hasGenesisConfigMap returns true if the genesis of the nodes is mounted from the genesis ConfigMap
+func hasGenesisConfigMap(instance *chainv1alpha1.Avalanchego) bool {
+	return instance.Status.GenesisConfigMap != ""
+}
+
+func (r *AvalanchegoReconciler) avagoGenesisConfigMap(
+	instance *chainv1alpha1.Avalanchego,
+	genesis string,
+	hash string,
+) *corev1.ConfigMap {
+	immutable := true
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      genesisConfigMapPrefix(instance) + "-" + hash[:genesisHashLength],
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				"app":           genesisConfigMapPrefix(instance),
+				deploymentLabel: instance.Spec.DeploymentName,
+			},
+		},
+		Immutable: &immutable,
+		Data: map[string]string{
+			genesisKey: genesis,
+		},
+	}
+	_ = controllerutil.SetControllerReference(instance, cm, r.Scheme) // TODO should we return this error if non-nil?
+	return cm
+}
+
+// ensureGenesis stores the genesis in its ConfigMap and references it from the status.
+// The genesis formerly copied into the status is dropped, it is kept in the network Secret or in spec.genesis.
+func (r *AvalanchegoReconciler) ensureGenesis(
+	ctx context.Context,
+	req ctrl.Request,
+	instance *chainv1alpha1.Avalanchego,
+	genesis string,
+	l logr.Logger,
+) error {
+	if genesis == "" {
+		instance.Status.GenesisConfigMap = ""
+		instance.Status.GenesisHash = ""
+		return nil
+	}
+	hash := genesisHash(genesis)
+	cm := r.avagoGenesisConfigMap(instance, genesis, hash)
+	if err := r.ensureConfigMap(ctx, req, instance, cm, l); err != nil {
+		return err
+	}
+	instance.Status.GenesisConfigMap = cm.Name
+	instance.Status.GenesisHash = hash
+	instance.Status.Genesis = ""
+	return nil
+}
+
+// removeStaleGenesis deletes the genesis ConfigMaps of the instance other than the current one,
+// and the genesis formerly copied into node Secrets.
+// Only called once all the nodes are up to date, none of them mounts a stale one anymore.
+func (r *AvalanchegoReconciler) removeStaleGenesis(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) error {
+	list := &corev1.ConfigMapList{}
+	if err := r.List(ctx, list, client.InNamespace(instance.Namespace), client.MatchingLabels{
+		"app": genesisConfigMapPrefix(instance),
+	}); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		cm := &list.Items[i]
+		if cm.Name == instance.Status.GenesisConfigMap || !metav1.IsControlledBy(cm, instance) {
+			continue
+		}
+		l.Info("Deleting stale genesis", "configMap", cm.Name)
+		if err := r.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	if !hasGenesisConfigMap(instance) {
+		return nil
+	}
+	// No node reads the genesis formerly copied into its Secret anymore
+	for i := 0; i < instance.Spec.NodeCount; i++ {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: avaGoPrefix + nodeBaseName(instance, i) + "-key", Namespace: instance.Namespace}, secret)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if _, ok := secret.Data[genesisKey]; !ok || !metav1.IsControlledBy(secret, instance) {
+			continue
+		}
+		l.Info("Removing the genesis from node Secret", "secret", secret.Name)
+		patch := client.MergeFrom(secret.DeepCopy())
+		delete(secret.Data, genesisKey)
+		if err := r.Patch(ctx, secret, patch); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepSecretGenesis copies the genesis formerly written into an existing node Secret into s,
+// so updating the Secret doesn't remove it before removeStaleGenesis does
+func (r *AvalanchegoReconciler) keepSecretGenesis(ctx context.Context, s *corev1.Secret) error {
+	found := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: s.Name, Namespace: s.Namespace}, found)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if genesis, ok := found.Data[genesisKey]; ok {
+		if s.Data == nil {
+			s.Data = map[string][]byte{}
+		}
+		s.Data[genesisKey] = genesis
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
+)
+
+// Keys of the network Secret.
+// The Secret holds the genesis and the staking key pairs of every node of a generated network,
+// node Secrets are copies of it.
+const (
+	networkGenesisKey = "genesis.json"
+	networkCertSuffix = ".crt"
+	networkKeySuffix  = ".key"
+	networkKeyPrefix  = "staker-"
+
+	eventKeyIssued        = "StakingKeyIssued"
+	eventSecretsMismatch  = "SecretsMismatch"
+	reasonSecretsMismatch = "SecretsMismatch"
+)
+
+func networkSecretName(instance *chainv1alpha1.Avalanchego) string {
+	return avaGoPrefix + instance.Spec.DeploymentName + "-network"
+}
+
+func networkCertKey(nodeId int) string {
+	return networkKeyPrefix + strconv.Itoa(nodeId) + networkCertSuffix
+}
+
+func networkKeyKey(nodeId int) string {
+	return networkKeyPrefix + strconv.Itoa(nodeId) + networkKeySuffix
+}
+
+// isGeneratedNetwork returns true if the genesis and the staking keys of the network are generated by the operator
+func isGeneratedNetwork(instance *chainv1alpha1.Avalanchego) bool {
+	return !instance.Spec.AttachesToNetwork() &&
+		instance.Spec.Genesis == "" &&
+		len(instance.Spec.ExistingSecrets) == 0 &&
+		len(instance.Spec.Certificates) == 0
+}
+
+func (r *AvalanchegoReconciler) avagoNetworkSecret(
+	instance *chainv1alpha1.Avalanchego,
+	network common.Network,
+) *corev1.Secret {
+	data := map[string][]byte{
+		networkGenesisKey: []byte(network.Genesis),
+	}
+	for i, keyPair := range network.KeyPairs {
+		if keyPair.Cert == "" {
+			continue
+		}
+		data[networkCertKey(i)] = []byte(keyPair.Cert)
+		data[networkKeyKey(i)] = []byte(keyPair.Key)
+	}
+	secr := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      networkSecretName(instance),
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				"app": networkSecretName(instance),
+			},
+		},
+		Type: "Opaque",
+		Data: data,
+	}
+	_ = controllerutil.SetControllerReference(instance, secr, r.Scheme) // TODO should we return this error if non-nil?
+	return secr
+}
+
+// ensureNetwork returns the generated network of the instance, as stored in the network Secret.
+// A new network is generated and stored, before any node object is created.
+// Existing networks whose network Secret is missing, because it was lost or predates the network Secret,
+// are rebuilt from the node Secrets and their genesis, they are never generated again.
+func (r *AvalanchegoReconciler) ensureNetwork(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) (common.Network, error) {
+	found := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: networkSecretName(instance), Namespace: instance.Namespace}, found)
+	if err == nil {
+		return networkFromSecret(found)
+	} else if !errors.IsNotFound(err) {
+		return common.Network{}, err
+	}
+
+	var network common.Network
+	if instance.Status.Genesis != "" || hasGenesisConfigMap(instance) {
+		l.Info("Storing existing network")
+		genesis, err := r.existingGenesis(ctx, instance)
+		if err != nil {
+			return common.Network{}, err
+		}
+		if network, err = r.networkFromNodeSecrets(ctx, instance, genesis); err != nil {
+			return common.Network{}, err
+		}
+	} else {
+		l.Info("Making new network")
+		genesis, err := common.RenderGenesis(&instance.Spec, instance.CreationTimestamp.Time)
+		if err != nil {
+			return common.Network{}, fmt.Errorf("couldn't render genesis: %w", err)
+		}
+		if network, err = common.NewNetwork(genesis); err != nil {
+			return common.Network{}, fmt.Errorf("couldn't make new network: %w", err)
+		}
+	}
+
+	// A single object is created atomically, so the genesis can't get out of sync with the keys
+	if err := r.Create(ctx, r.avagoNetworkSecret(instance, network)); err != nil {
+		return common.Network{}, err
+	}
+	return network, nil
+}
+
+// existingGenesis returns the genesis of a network created before, from the status of networks which predate
+// the genesis ConfigMap, from the genesis ConfigMap, or from the copy formerly written into the node Secrets
+func (r *AvalanchegoReconciler) existingGenesis(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+) (string, error) {
+	if instance.Status.Genesis != "" {
+		return instance.Status.Genesis, nil
+	}
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: instance.Status.GenesisConfigMap, Namespace: instance.Namespace}, cm)
+	if err == nil && genesisHash(cm.Data[genesisKey]) == instance.Status.GenesisHash {
+		return cm.Data[genesisKey], nil
+	} else if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	for i := 0; i < instance.Spec.NodeCount; i++ {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: getSecretName(*instance, i), Namespace: instance.Namespace}, secret)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		if genesis := string(secret.Data[genesisKey]); genesis != "" && genesisHash(genesis) == instance.Status.GenesisHash {
+			return genesis, nil
+		}
+	}
+	return "", fmt.Errorf("network secret %s is missing and genesis %s is not found, not generating a new network", networkSecretName(instance), instance.Status.GenesisHash)
+}
+
+// networkFromSecret reads the genesis and the key pairs from a network Secret.
+// Indexes without a key pair are left empty.
+func networkFromSecret(secret *corev1.Secret) (common.Network, error) {
+	network := common.Network{
+		Genesis: string(secret.Data[networkGenesisKey]),
+	}
+	for k := range secret.Data {
+		if !strings.HasPrefix(k, networkKeyPrefix) || !strings.HasSuffix(k, networkCertSuffix) {
+			continue
+		}
+		nodeId, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(k, networkKeyPrefix), networkCertSuffix))
+		if err != nil {
+			continue
+		}
+		for len(network.KeyPairs) <= nodeId {
+			network.KeyPairs = append(network.KeyPairs, common.KeyPair{})
+		}
+		cert := string(secret.Data[k])
+		id, err := common.NodeIDFromCert(cert)
+		if err != nil {
+			return common.Network{}, fmt.Errorf("invalid certificate %s in network secret: %w", k, err)
+		}
+		network.KeyPairs[nodeId] = common.KeyPair{
+			Cert: cert,
+			Key:  string(secret.Data[networkKeyKey(nodeId)]),
+			Id:   id,
+		}
+	}
+	return network, nil
+}
+
+// networkFromNodeSecrets rebuilds the network of an instance from its node Secrets and its genesis.
+// Returns an error if the key pair of a genesis staker which is still a node can't be found,
+// a new one would replace the staker.
+func (r *AvalanchegoReconciler) networkFromNodeSecrets(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	genesis string,
+) (common.Network, error) {
+	stakers, err := common.GenesisStakers(genesis)
+	if err != nil {
+		return common.Network{}, err
+	}
+	// Genesis stakers removed by lowering nodeCount keep their Secret, unless storageRetentionPolicy is Delete
+	count := instance.Spec.NodeCount
+	if len(stakers) > count {
+		count = len(stakers)
+	}
+	network := common.Network{
+		Genesis:  genesis,
+		KeyPairs: make([]common.KeyPair, count),
+	}
+	for i := 0; i < count; i++ {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: getSecretName(*instance, i), Namespace: instance.Namespace}, secret)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return common.Network{}, err
+		}
+		cert := string(secret.Data["staker.crt"])
+		if cert == "" {
+			continue
+		}
+		id, err := common.NodeIDFromCert(cert)
+		if err != nil {
+			return common.Network{}, err
+		}
+		network.KeyPairs[i] = common.KeyPair{
+			Cert: cert,
+			Key:  string(secret.Data["staker.key"]),
+			Id:   id,
+		}
+	}
+	for i, staker := range stakers {
+		if i < instance.Spec.NodeCount && network.KeyPairs[i].Id != staker {
+			return common.Network{}, fmt.Errorf("network secret %s is missing and the key pair of genesis staker %s of node %d is not found, not generating a new one", networkSecretName(instance), staker, i)
+		}
+	}
+	return network, nil
+}
+
+// ensureNetworkKeyPair returns the key pair of a node of a generated network.
+// Nodes added by raising nodeCount get a new key pair, which is stored in the network Secret before it is used.
+// They join the network as peers, not as genesis stakers.
+func (r *AvalanchegoReconciler) ensureNetworkKeyPair(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	network *common.Network,
+	nodeId int,
+	l logr.Logger,
+) (common.KeyPair, error) {
+	if nodeId < len(network.KeyPairs) && network.KeyPairs[nodeId].Cert != "" {
+		return network.KeyPairs[nodeId], nil
+	}
+
+	keyPair, err := common.NewKeyPair()
+	if err != nil {
+		return common.KeyPair{}, err
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: networkSecretName(instance), Namespace: instance.Namespace}, secret); err != nil {
+		return common.KeyPair{}, err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[networkCertKey(nodeId)] = []byte(keyPair.Cert)
+	secret.Data[networkKeyKey(nodeId)] = []byte(keyPair.Key)
+	// Fails on conflicting updates, so a key pair is never replaced once stored
+	if err := r.Update(ctx, secret); err != nil {
+		return common.KeyPair{}, err
+	}
+	l.Info("Issued staking key", "node", nodeId, "nodeID", keyPair.Id)
+	r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventKeyIssued,
+		"Issued a staking key for node %d, %s is not a genesis staker and has to be added as a validator", nodeId, keyPair.Id)
+
+	for len(network.KeyPairs) <= nodeId {
+		network.KeyPairs = append(network.KeyPairs, common.KeyPair{})
+	}
+	network.KeyPairs[nodeId] = keyPair
+	return keyPair, nil
+}
+
+// removeNetworkKeyPair deletes the key pair of a removed node from the network Secret.
+// Key pairs of genesis stakers are kept: they are part of the network, and are reused if nodeCount is raised again.
+func (r *AvalanchegoReconciler) removeNetworkKeyPair(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	nodeId int,
+) error {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: networkSecretName(instance), Namespace: instance.Namespace}, secret)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if _, ok := secret.Data[networkCertKey(nodeId)]; !ok {
+		return nil
+	}
+	stakers, err := common.GenesisStakers(string(secret.Data[networkGenesisKey]))
+	if err != nil {
+		return err
+	}
+	// Genesis stakers are generated in the order of the nodes
+	if nodeId < len(stakers) {
+		return nil
+	}
+	delete(secret.Data, networkCertKey(nodeId))
+	delete(secret.Data, networkKeyKey(nodeId))
+	return r.Update(ctx, secret)
+}
+
+// checkNetwork compares the network Secret with the genesis stakers, and the node Secrets with the network Secret.
+// Returns a description of every mismatch found.
+func (r *AvalanchegoReconciler) checkNetwork(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	network common.Network,
+) ([]string, error) {
+	var problems []string
+	stakers, err := common.GenesisStakers(network.Genesis)
+	if err != nil {
+		return nil, err
+	}
+	// Genesis stakers are generated in the order of the nodes
+	for i, staker := range stakers {
+		if i >= len(network.KeyPairs) || network.KeyPairs[i].Id != staker {
+			problems = append(problems, fmt.Sprintf("network secret has no key pair for genesis staker %s of node %d", staker, i))
+		}
+	}
+
+	for i := 0; i < instance.Spec.NodeCount && i < len(network.KeyPairs); i++ {
+		if network.KeyPairs[i].Cert == "" {
+			continue
+		}
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: getSecretName(*instance, i), Namespace: instance.Namespace}, secret)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		id, err := common.NodeIDFromCert(string(secret.Data["staker.crt"]))
+		if err != nil || id != network.KeyPairs[i].Id {
+			problems = append(problems, fmt.Sprintf("secret %s does not hold the key pair of %s", secret.Name, network.KeyPairs[i].Id))
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+// reportNetworkCheck sets the SecretsReady condition from the result of checkNetwork
+func (r *AvalanchegoReconciler) reportNetworkCheck(instance *chainv1alpha1.Avalanchego, problems []string) {
+	if len(problems) == 0 {
+		setCondition(instance, chainv1alpha1.ConditionSecretsReady, metav1.ConditionTrue, reasonSecretsCreated, "")
+		return
+	}
+	message := strings.Join(problems, "; ")
+	setCondition(instance, chainv1alpha1.ConditionSecretsReady, metav1.ConditionFalse, reasonSecretsMismatch, message)
+	r.Recorder.Event(instance, corev1.EventTypeWarning, eventSecretsMismatch, message)
+}
@@ -17,50 +17,28 @@ limitations under the License.
 package controllers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"strconv"
 
 	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
-
-	avalanchegoConstants "github.com/lasthyphen/dijigo/utils/constants"
+	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
 )
 
-func (r *AvalanchegoReconciler) avagoConfigMap(
-	instance *chainv1alpha1.Avalanchego,
-	name string,
-	script string,
-) *corev1.ConfigMap {
-	data := make(map[string]string)
-	data["config.sh"] = string(script)
-	cm := &corev1.ConfigMap{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "ConfigMap",
-			APIVersion: "v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: instance.Namespace,
-			Labels: map[string]string{
-				"app": avaGoPrefix + name,
-			},
-		},
-		Data: data,
-	}
-	_ = controllerutil.SetControllerReference(instance, cm, r.Scheme) // TODO should we return this error if non-nil?
-	return cm
-}
-
 func (r *AvalanchegoReconciler) avagoSecret(
 	instance *chainv1alpha1.Avalanchego,
 	name string,
 	certificate string,
 	key string,
-	genesis string,
 ) *corev1.Secret {
 	secr := &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
@@ -76,19 +54,71 @@ func (r *AvalanchegoReconciler) avagoSecret(
 		},
 		Type: "Opaque",
 		StringData: map[string]string{
-			"staker.crt":   certificate,
-			"staker.key":   key,
-			"genesis.json": genesis,
+			"staker.crt": certificate,
+			"staker.key": key,
 		},
 	}
 	_ = controllerutil.SetControllerReference(instance, secr, r.Scheme) // TODO should we return this error if non-nil?
 	return secr
 }
 
+const (
+	// chainConfigDir is the chain config dir of the nodes, the C-Chain config is mounted into it
+	chainConfigDir = "/etc/avalanchego/chains"
+
+	// cChainConfigKey is the key of the C-Chain config in the chain config ConfigMap
+	cChainConfigKey = "C.json"
+
+	// chainConfigHashAnnotation is set on the pods to the hash of their chain config, so they restart when it changes
+	chainConfigHashAnnotation = "chain.djtx.network/chain-config-hash"
+)
+
+func chainConfigMapName(instance *chainv1alpha1.Avalanchego) string {
+	return avaGoPrefix + instance.Spec.DeploymentName + "-chain-config"
+}
+
+// cChainConfig returns the config.json of the C-Chain of the nodes, empty if spec.cChain.config isn't given
+func cChainConfig(instance *chainv1alpha1.Avalanchego) string {
+	config := common.RenderChainConfig(&instance.Spec)
+	if config == nil {
+		return ""
+	}
+	b, _ := json.Marshal(config)
+	return string(b)
+}
+
+// avagoChainConfigMap returns the ConfigMap holding the chain configs of the nodes, nil if there are none
+func (r *AvalanchegoReconciler) avagoChainConfigMap(instance *chainv1alpha1.Avalanchego) *corev1.ConfigMap {
+	config := cChainConfig(instance)
+	if config == "" {
+		return nil
+	}
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      chainConfigMapName(instance),
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				"app":           chainConfigMapName(instance),
+				deploymentLabel: instance.Spec.DeploymentName,
+			},
+		},
+		Data: map[string]string{
+			cChainConfigKey: config,
+		},
+	}
+	_ = controllerutil.SetControllerReference(instance, cm, r.Scheme) // TODO should we return this error if non-nil?
+	return cm
+}
+
 func (r *AvalanchegoReconciler) avagoService(
 	instance *chainv1alpha1.Avalanchego,
-	name string,
+	nodeId int,
 ) *corev1.Service {
+	name := nodeBaseName(instance, nodeId)
 	svc := &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Service",
@@ -103,19 +133,56 @@ func (r *AvalanchegoReconciler) avagoService(
 		},
 		Spec: corev1.ServiceSpec{
 			ClusterIP: "None",
+			// Nodes find their peers through these services while bootstrapping, before they are ready
+			PublishNotReadyAddresses: true,
 			Selector: map[string]string{
 				"app": avaGoPrefix + name,
 			},
 			Ports: []corev1.ServicePort{
 				{
-					Name:     "http",
-					Protocol: "TCP",
-					Port:     9650,
+					Name:       "http",
+					Protocol:   "TCP",
+					Port:       9650,
+					TargetPort: intstr.FromInt(9650),
+				},
+				{
+					Name:       "staking",
+					Protocol:   "TCP",
+					Port:       9651,
+					TargetPort: intstr.FromInt(9651),
 				},
+			},
+		},
+	}
+	_ = controllerutil.SetControllerReference(instance, svc, r.Scheme) // TODO should we return this error if non-nil?
+	return svc
+}
+
+// avagoAPIService returns the Service routing API requests to the nodes which are ready, see getProbes
+func (r *AvalanchegoReconciler) avagoAPIService(instance *chainv1alpha1.Avalanchego) *corev1.Service {
+	name := avaGoPrefix + instance.Spec.DeploymentName + "-api"
+	svc := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				"app": name,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				deploymentLabel: instance.Spec.DeploymentName,
+			},
+			Ports: []corev1.ServicePort{
 				{
-					Name:     "staking",
-					Protocol: "TCP",
-					Port:     9651,
+					Name:       "http",
+					Protocol:   "TCP",
+					Port:       9650,
+					TargetPort: intstr.FromInt(9650),
 				},
 			},
 		},
@@ -126,8 +193,15 @@ func (r *AvalanchegoReconciler) avagoService(
 
 func (r *AvalanchegoReconciler) avagoPVC(
 	instance *chainv1alpha1.Avalanchego,
-	name string,
+	nodeId int,
 ) *corev1.PersistentVolumeClaim {
+	name := nodeBaseName(instance, nodeId)
+	storage := instance.Spec.WithDefaults().Storage
+	size := *storage.Size
+	// Automatic expansions outgrow the spec size
+	if expansion := volumeExpansion(instance, avaGoPrefix+name+"-pvc"); expansion != nil && expansion.Size.Cmp(size) > 0 {
+		size = expansion.Size
+	}
 	pvc := &corev1.PersistentVolumeClaim{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "PersistentVolumeClaim",
@@ -141,10 +215,13 @@ func (r *AvalanchegoReconciler) avagoPVC(
 			},
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
-			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			AccessModes:      storage.AccessModes,
+			StorageClassName: storage.StorageClassName,
+			VolumeMode:       storage.VolumeMode,
+			DataSource:       pvcDataSource(instance),
 			Resources: corev1.ResourceRequirements{
 				Requests: corev1.ResourceList{
-					corev1.ResourceStorage: resource.MustParse("50Gi"),
+					corev1.ResourceStorage: size,
 				},
 			},
 		},
@@ -155,34 +232,60 @@ func (r *AvalanchegoReconciler) avagoPVC(
 
 func (r *AvalanchegoReconciler) avagoStatefulSet(
 	instance *chainv1alpha1.Avalanchego,
-	name string,
 	nodeId int,
 ) *appsv1.StatefulSet {
+	name := nodeBaseName(instance, nodeId)
 	var initContainers []corev1.Container
 	envVars := r.getEnvVars(instance)
-	volumeMounts := r.getVolumeMounts(instance, name)
+	volumeMounts := r.getVolumeMounts(instance, name, nodeId)
 	volumes := r.getVolumes(instance, name, nodeId)
 	podLables := map[string]string{}
 
 	podLables["app"] = avaGoPrefix + name
-	podLables["tags.datadoghq.com/version"] = instance.Spec.Tag
+	podLables[deploymentLabel] = instance.Spec.DeploymentName
+	image, tag := nodeImage(instance, nodeId)
+	podLables["tags.datadoghq.com/version"] = tag
 	podLables = mergeMaps(podLables, instance.Spec.PodLabels)
 
-	index := name[len(name)-1:]
-	if (index == "0") && (instance.Spec.BootstrapperURL == "") {
+	switch {
+	case usesBootstrapConfig(instance, nodeId):
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "AVAGO_CONFIG_FILE",
+			Value: "/etc/avalanchego/conf/" + bootstrapConfigKey,
+		})
+	case instance.Spec.JoinsPublicNetwork():
+		// Nodes bootstrap from the beacons built into avalanchego
+	default:
 		envVars = append(envVars, corev1.EnvVar{
 			Name:  "AVAGO_BOOTSTRAP_IPS",
 			Value: "",
 		})
 	}
-	if (index != "0") || (instance.Spec.BootstrapperURL != "") {
-		initContainers = r.getAvagoInitContainer(instance)
+
+	podAnnotations := instance.Spec.PodAnnotations
+	// Validation rejects AVAGO_CHAIN_CONFIG_DIR in env combined with cChain.config
+	if config := cChainConfig(instance); config != "" {
 		envVars = append(envVars, corev1.EnvVar{
-			Name:  "AVAGO_CONFIG_FILE",
-			Value: "/etc/avalanchego/conf/conf.json",
+			Name:  "AVAGO_CHAIN_CONFIG_DIR",
+			Value: chainConfigDir,
+		})
+		podAnnotations = mergeMaps(podAnnotations, map[string]string{
+			chainConfigHashAnnotation: specHash(config),
 		})
 	}
 
+	if restore := r.getRestoreInitContainer(instance, name); restore != nil {
+		// The database is in place before anything else runs
+		initContainers = append([]corev1.Container{*restore}, initContainers...)
+	}
+
+	startupProbe, readinessProbe, livenessProbe := r.getProbes(instance)
+
+	replicas := int32(1)
+	if isStoppedForBackup(instance, nodeId) {
+		replicas = 0
+	}
+
 	sts := &appsv1.StatefulSet{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "StatefulSet",
@@ -197,7 +300,7 @@ func (r *AvalanchegoReconciler) avagoStatefulSet(
 		},
 		Spec: appsv1.StatefulSetSpec{
 			// A hack to create a literal *int32 vatiable, set to 1
-			Replicas:            &[]int32{1}[0],
+			Replicas:            &[]int32{replicas}[0],
 			PodManagementPolicy: "OrderedReady",
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
@@ -207,7 +310,7 @@ func (r *AvalanchegoReconciler) avagoStatefulSet(
 			ServiceName: avaGoPrefix + name + "-service",
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Annotations: instance.Spec.PodAnnotations,
+					Annotations: podAnnotations,
 					Labels:      podLables,
 					//TODO Add checksum for cert/key
 				},
@@ -216,7 +319,7 @@ func (r *AvalanchegoReconciler) avagoStatefulSet(
 					Containers: []corev1.Container{
 						{
 							Name:            "avago",
-							Image:           instance.Spec.Image + ":" + instance.Spec.Tag,
+							Image:           image + ":" + tag,
 							ImagePullPolicy: "IfNotPresent",
 							Resources: corev1.ResourceRequirements{
 								Requests: corev1.ResourceList{
@@ -228,8 +331,11 @@ func (r *AvalanchegoReconciler) avagoStatefulSet(
 									corev1.ResourceMemory: resource.MustParse("2Gi"),
 								},
 							},
-							Env:          envVars,
-							VolumeMounts: volumeMounts,
+							Env:            envVars,
+							VolumeMounts:   volumeMounts,
+							StartupProbe:   startupProbe,
+							ReadinessProbe: readinessProbe,
+							LivenessProbe:  livenessProbe,
 							Ports: []corev1.ContainerPort{
 								{
 									Name:          "http",
@@ -246,6 +352,7 @@ func (r *AvalanchegoReconciler) avagoStatefulSet(
 					},
 					ImagePullSecrets: instance.Spec.ImagePullSecrets,
 					Volumes:          volumes,
+					ReadinessGates:   r.getReadinessGates(instance),
 				},
 			},
 			// VolumeClaimTemplates: volumeClaim,
@@ -255,48 +362,14 @@ func (r *AvalanchegoReconciler) avagoStatefulSet(
 	if !reflect.DeepEqual(instance.Spec.Resources, corev1.ResourceRequirements{}) {
 		sts.Spec.Template.Spec.Containers[0].Resources = instance.Spec.Resources
 	}
+	sts.Annotations = map[string]string{
+		specHashAnnotation: specHash(sts.Spec),
+	}
 
 	_ = controllerutil.SetControllerReference(instance, sts, r.Scheme) // TODO should we return this error if non-nil?
 	return sts
 }
 
-func (r *AvalanchegoReconciler) getAvagoInitContainer(instance *chainv1alpha1.Avalanchego) []corev1.Container {
-	initContainers := []corev1.Container{
-		{
-			Name:  "init-bootnode-ip",
-			Image: "avaplatform/dnsutils:1.0.0",
-			Env: []corev1.EnvVar{
-				{
-					Name:  "CONFIG_PATH",
-					Value: "/tmp/conf",
-				},
-				{
-					Name:  "BOOTSTRAPPERS",
-					Value: instance.Status.BootstrapperURL,
-				},
-			},
-			Command: []string{
-				"sh",
-				"-c",
-				"/tmp/script/config.sh",
-			},
-			VolumeMounts: []corev1.VolumeMount{
-				{
-					Name:      "avalanchego-init-script",
-					MountPath: "/tmp/script",
-					ReadOnly:  true,
-				},
-				{
-					Name:      "init-volume",
-					MountPath: "/tmp/conf",
-					ReadOnly:  false,
-				},
-			},
-		},
-	}
-	return initContainers
-}
-
 func (r *AvalanchegoReconciler) getEnvVars(instance *chainv1alpha1.Avalanchego) []corev1.EnvVar {
 	envVars := []corev1.EnvVar{
 		{
@@ -313,7 +386,7 @@ func (r *AvalanchegoReconciler) getEnvVars(instance *chainv1alpha1.Avalanchego)
 		},
 		{
 			Name:  "AVAGO_NETWORK_ID",
-			Value: "12346",
+			Value: strconv.FormatUint(uint64(instance.Spec.GetNetworkID()), 10),
 		},
 		{
 			Name:  "AVAGO_STAKING_ENABLED",
@@ -334,7 +407,7 @@ func (r *AvalanchegoReconciler) getEnvVars(instance *chainv1alpha1.Avalanchego)
 	}
 
 	//Append certificates, if it is a new network or cert or existing secrets are provided
-	if (instance.Spec.BootstrapperURL == "") || (len(instance.Spec.Certificates) > 0) || len(instance.Spec.ExistingSecrets) > 0 {
+	if !instance.Spec.AttachesToNetwork() || (len(instance.Spec.Certificates) > 0) || len(instance.Spec.ExistingSecrets) > 0 {
 		envVars = append(envVars, corev1.EnvVar{
 			Name:  "AVAGO_STAKING_TLS_CERT_FILE",
 			Value: "/etc/avalanchego/st-certs/staker.crt",
@@ -344,6 +417,14 @@ func (r *AvalanchegoReconciler) getEnvVars(instance *chainv1alpha1.Avalanchego)
 		})
 	}
 
+	// A lone node has no peer to connect to, the network health check would never pass
+	if instance.Spec.NodeCount == 1 && !instance.Spec.AttachesToNetwork() {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "AVAGO_NETWORK_HEALTH_MIN_CONN_PEERS",
+			Value: "0",
+		})
+	}
+
 	for _, v := range instance.Spec.Env {
 		envVarsI := indexOf(envVars, v.Name)
 		if envVarsI == -1 {
@@ -354,20 +435,18 @@ func (r *AvalanchegoReconciler) getEnvVars(instance *chainv1alpha1.Avalanchego)
 	}
 
 	// Adding AVAGO_GENESIS env var only for custom networks
-	for _, v := range envVars {
-		switch v.Name {
-		case "AVAGO_NETWORK_ID":
-			if networkId, err := strconv.Atoi(v.Value); err == nil {
-				if uint32(networkId) != avalanchegoConstants.MainnetID &&
-					uint32(networkId) != avalanchegoConstants.FujiID &&
-					uint32(networkId) != avalanchegoConstants.LocalID {
-					envVars = append(envVars, corev1.EnvVar{
-						Name:  "AVAGO_GENESIS",
-						Value: "/etc/avalanchego/st-certs/genesis.json",
-					})
-				}
-			}
-		}
+	// The genesis comes from the genesis ConfigMap, or from existingSecrets
+	switch {
+	case hasGenesisConfigMap(instance):
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "AVAGO_GENESIS",
+			Value: genesisDir + "/" + genesisKey,
+		})
+	case instance.Spec.IsCustomNetwork():
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "AVAGO_GENESIS",
+			Value: "/etc/avalanchego/st-certs/genesis.json",
+		})
 	}
 
 	return envVars
@@ -383,71 +462,116 @@ func indexOf(env []corev1.EnvVar, name string) int {
 	return -1
 }
 
-func (r *AvalanchegoReconciler) getVolumeMounts(instance *chainv1alpha1.Avalanchego, name string) []corev1.VolumeMount {
-	return []corev1.VolumeMount{
+func (r *AvalanchegoReconciler) getVolumeMounts(instance *chainv1alpha1.Avalanchego, name string, nodeId int) []corev1.VolumeMount {
+	volumeMounts := []corev1.VolumeMount{
 		{
 			Name:      avaGoPrefix + "db-" + name,
 			MountPath: "/root/.avalanchego",
 			ReadOnly:  false,
 		},
-		{
-			Name:      "init-volume",
-			MountPath: "/etc/avalanchego/conf",
-			ReadOnly:  true,
-		},
 		{
 			Name:      avaGoPrefix + "cert-" + name,
 			MountPath: "/etc/avalanchego/st-certs",
 			ReadOnly:  true,
 		},
 	}
+	if usesBootstrapConfig(instance, nodeId) {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "bootstrap-config",
+			MountPath: "/etc/avalanchego/conf",
+			ReadOnly:  true,
+		})
+	}
+	if hasGenesisConfigMap(instance) {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "genesis",
+			MountPath: genesisDir,
+			ReadOnly:  true,
+		})
+	}
+	if cChainConfig(instance) != "" {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "chain-config",
+			MountPath: chainConfigDir,
+			ReadOnly:  true,
+		})
+	}
+	return volumeMounts
 }
 
 func (r *AvalanchegoReconciler) getVolumes(instance *chainv1alpha1.Avalanchego, name string, nodeId int) []corev1.Volume {
+	secretName := getSecretName(*instance, nodeId)
 
-	var secretName string
-	if len(instance.Spec.ExistingSecrets) > 0 {
-		secretName = instance.Spec.ExistingSecrets[nodeId]
-	} else {
-		secretName = avaGoPrefix + name + "-key"
+	dbVolume := corev1.VolumeSource{
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: avaGoPrefix + name + "-pvc",
+		},
+	}
+	if instance.Spec.Storage.EmptyDir {
+		dbVolume = corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		}
 	}
 
-	return []corev1.Volume{
+	volumes := []corev1.Volume{
+		{
+			Name:         avaGoPrefix + "db-" + name,
+			VolumeSource: dbVolume,
+		},
 		{
-			Name: avaGoPrefix + "db-" + name,
+			Name: avaGoPrefix + "cert-" + name,
 			VolumeSource: corev1.VolumeSource{
-				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: avaGoPrefix + name + "-pvc",
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: secretName,
 				},
 			},
 		},
-		{
-			Name: "avalanchego-init-script",
+	}
+	if usesBootstrapConfig(instance, nodeId) {
+		volumes = append(volumes, corev1.Volume{
+			Name: "bootstrap-config",
 			VolumeSource: corev1.VolumeSource{
 				ConfigMap: &corev1.ConfigMapVolumeSource{
 					LocalObjectReference: corev1.LocalObjectReference{
-						Name: avaGoPrefix + instance.Spec.DeploymentName + "init-script",
+						Name: bootstrapConfigMapName(instance),
+					},
+					// The pod waits to start until the config is written, the key is missing until the
+					// bootstrappers are known
+					Items: []corev1.KeyToPath{
+						{Key: bootstrapConfigKeyOf(instance, nodeId), Path: bootstrapConfigKey},
 					},
-					// A hack to create a literal *int32 vatiable, set to 0777
-					DefaultMode: &[]int32{0777}[0],
 				},
 			},
-		},
-		{
-			Name: "init-volume",
+		})
+	}
+	if hasGenesisConfigMap(instance) {
+		volumes = append(volumes, corev1.Volume{
+			Name: "genesis",
 			VolumeSource: corev1.VolumeSource{
-				EmptyDir: &corev1.EmptyDirVolumeSource{},
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: instance.Status.GenesisConfigMap,
+					},
+				},
 			},
-		},
-		{
-			Name: avaGoPrefix + "cert-" + name,
+		})
+	}
+	if cChainConfig(instance) != "" {
+		volumes = append(volumes, corev1.Volume{
+			Name: "chain-config",
 			VolumeSource: corev1.VolumeSource{
-				Secret: &corev1.SecretVolumeSource{
-					SecretName: secretName,
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: chainConfigMapName(instance),
+					},
+					Items: []corev1.KeyToPath{
+						{Key: cChainConfigKey, Path: "C/config.json"},
+					},
 				},
 			},
-		},
+		})
 	}
+	return volumes
 }
 
 func mergeMaps(ms ...map[string]string) map[string]string {
@@ -459,3 +583,12 @@ func mergeMaps(ms ...map[string]string) map[string]string {
 	}
 	return res
 }
+
+// specHash returns a hash of the desired spec of an object.
+// Comparing it to the hash annotated on the existing object avoids diffing against fields defaulted by the API server.
+func specHash(spec interface{}) string {
+	// Maps are marshalled with sorted keys, so the result is stable
+	b, _ := json.Marshal(spec)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
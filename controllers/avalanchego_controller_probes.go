@@ -0,0 +1,148 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+)
+
+// bootstrappedCondition is the readiness gate of the pods, set by the operator once info.isBootstrapped reports
+// the primary network chains as bootstrapped
+const bootstrappedCondition corev1.PodConditionType = "chain.djtx.network/bootstrapped"
+
+// Default thresholds of the probes, see chainv1alpha1.ProbesSpec
+var (
+	defaultStartupProbe = corev1.Probe{
+		PeriodSeconds:    10,
+		TimeoutSeconds:   5,
+		FailureThreshold: 60,
+	}
+	defaultReadinessProbe = corev1.Probe{
+		PeriodSeconds:    10,
+		TimeoutSeconds:   5,
+		FailureThreshold: 3,
+	}
+	defaultLivenessProbe = corev1.Probe{
+		PeriodSeconds:    30,
+		TimeoutSeconds:   5,
+		FailureThreshold: 5,
+	}
+)
+
+// getProbes returns the startup, readiness and liveness probes of the avalanchego container, nil for disabled ones
+func (r *AvalanchegoReconciler) getProbes(instance *chainv1alpha1.Avalanchego) (*corev1.Probe, *corev1.Probe, *corev1.Probe) {
+	httpPort := corev1.Handler{
+		TCPSocket: &corev1.TCPSocketAction{
+			Port: intstr.FromString("http"),
+		},
+	}
+	health := corev1.Handler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path: "/ext/health",
+			Port: intstr.FromString("http"),
+		},
+	}
+	probes := instance.Spec.Probes
+	return probe(defaultStartupProbe, httpPort, probes.Startup),
+		probe(defaultReadinessProbe, health, probes.Readiness),
+		probe(defaultLivenessProbe, httpPort, probes.Liveness)
+}
+
+// probe returns the default probe with the thresholds given in the spec, or nil if it is disabled
+func probe(defaults corev1.Probe, handler corev1.Handler, spec chainv1alpha1.ProbeSpec) *corev1.Probe {
+	if spec.Disabled {
+		return nil
+	}
+	p := defaults
+	p.Handler = handler
+	if spec.InitialDelaySeconds > 0 {
+		p.InitialDelaySeconds = spec.InitialDelaySeconds
+	}
+	if spec.PeriodSeconds > 0 {
+		p.PeriodSeconds = spec.PeriodSeconds
+	}
+	if spec.TimeoutSeconds > 0 {
+		p.TimeoutSeconds = spec.TimeoutSeconds
+	}
+	if spec.FailureThreshold > 0 {
+		p.FailureThreshold = spec.FailureThreshold
+	}
+	return &p
+}
+
+// getReadinessGates returns the readiness gates of the pods.
+// The bootstrapped gate is only set if the operator can query the nodes, otherwise the pods would never be ready.
+func (r *AvalanchegoReconciler) getReadinessGates(instance *chainv1alpha1.Avalanchego) []corev1.PodReadinessGate {
+	if instance.Spec.Probes.Readiness.Disabled || r.NodeAPI == nil {
+		return nil
+	}
+	return []corev1.PodReadinessGate{
+		{ConditionType: bootstrappedCondition},
+	}
+}
+
+// hasBootstrappedGate returns true if the readiness of the pod depends on the bootstrapped condition
+func hasBootstrappedGate(pod *corev1.Pod) bool {
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == bootstrappedCondition {
+			return true
+		}
+	}
+	return false
+}
+
+// setBootstrappedCondition records in the pod status whether the node has bootstrapped, if it changed
+func (r *AvalanchegoReconciler) setBootstrappedCondition(ctx context.Context, pod *corev1.Pod, bootstrapped bool) error {
+	condition := corev1.PodCondition{
+		Type:               bootstrappedCondition,
+		Status:             corev1.ConditionFalse,
+		Reason:             "Bootstrapping",
+		Message:            "The node is bootstrapping the primary network",
+		LastTransitionTime: metav1.Now(),
+	}
+	if bootstrapped {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "Bootstrapped"
+		condition.Message = "The node has bootstrapped the primary network"
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == bootstrappedCondition && c.Status == condition.Status {
+			return nil
+		}
+	}
+	// The conditions are merged by type, the ones of the kubelet are left alone
+	patch := client.StrategicMergeFrom(pod.DeepCopy())
+	pod.Status.Conditions = append(removeCondition(pod.Status.Conditions, bootstrappedCondition), condition)
+	return r.Status().Patch(ctx, pod, patch)
+}
+
+func removeCondition(conditions []corev1.PodCondition, conditionType corev1.PodConditionType) []corev1.PodCondition {
+	res := make([]corev1.PodCondition, 0, len(conditions))
+	for _, c := range conditions {
+		if c.Type != conditionType {
+			res = append(res, c)
+		}
+	}
+	return res
+}
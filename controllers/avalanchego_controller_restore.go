@@ -0,0 +1,191 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
+)
+
+// restoreContainerName is the name of the init container unpacking spec.dataSource.archive
+const restoreContainerName = "restore-db"
+
+// pvcDataSource returns the source new PVCs are provisioned from, or nil if they start empty
+func pvcDataSource(instance *chainv1alpha1.Avalanchego) *corev1.TypedLocalObjectReference {
+	source := instance.Spec.DataSource
+	switch {
+	case source == nil:
+		return nil
+	case source.VolumeSnapshot != "":
+		return &corev1.TypedLocalObjectReference{
+			APIGroup: &volumeSnapshotGVK.Group,
+			Kind:     volumeSnapshotGVK.Kind,
+			Name:     source.VolumeSnapshot,
+		}
+	case source.PersistentVolumeClaim != "":
+		return &corev1.TypedLocalObjectReference{
+			Kind: "PersistentVolumeClaim",
+			Name: source.PersistentVolumeClaim,
+		}
+	}
+	return nil
+}
+
+// getRestoreInitContainer returns the init container seeding the database with spec.dataSource.archive,
+// or nil if there is no archive. The database is only seeded if it is empty, see common.AvagoRestoreArchiveScript.
+func (r *AvalanchegoReconciler) getRestoreInitContainer(instance *chainv1alpha1.Avalanchego, name string) *corev1.Container {
+	if instance.Spec.DataSource == nil || instance.Spec.DataSource.Archive == nil {
+		return nil
+	}
+	archive := instance.Spec.WithDefaults().DataSource.Archive
+	return &corev1.Container{
+		Name:  restoreContainerName,
+		Image: archive.Image,
+		Env: []corev1.EnvVar{
+			{
+				Name:  "DB_DIR",
+				Value: "/root/.avalanchego",
+			},
+			{
+				Name:  "ARCHIVE_URL",
+				Value: archive.URL,
+			},
+			{
+				Name:  "ARCHIVE_SHA256",
+				Value: strings.ToLower(archive.SHA256),
+			},
+		},
+		Command: []string{
+			"sh",
+			"-c",
+			common.AvagoRestoreArchiveScript,
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      avaGoPrefix + "db-" + name,
+				MountPath: "/root/.avalanchego",
+				ReadOnly:  false,
+			},
+		},
+		// The volume belongs to root, like the database written by avalanchego
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser: &[]int64{0}[0],
+		},
+	}
+}
+
+// observeRestore returns the outcome of seeding the database of a node from spec.dataSource.
+// A restored PVC is observed through its phase, an archive through the termination message of the init container.
+// Returns the previous outcome if nothing new is known, e.g. the PVC or the pod predates spec.dataSource.
+func (r *AvalanchegoReconciler) observeRestore(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	nodeId int,
+) (*chainv1alpha1.RestoreStatus, error) {
+	var previous *chainv1alpha1.RestoreStatus
+	if nodeId < len(instance.Status.Nodes) && instance.Status.Nodes[nodeId].Index == nodeId {
+		previous = instance.Status.Nodes[nodeId].Restore
+	}
+	source := instance.Spec.DataSource
+	if source == nil || (previous != nil && (previous.Phase == chainv1alpha1.RestoreSucceeded || previous.Phase == chainv1alpha1.RestoreSkipped)) {
+		return previous, nil
+	}
+	name := avaGoPrefix + nodeBaseName(instance, nodeId)
+
+	if source.Archive == nil {
+		pvc := &corev1.PersistentVolumeClaim{}
+		err := r.Get(ctx, types.NamespacedName{Name: name + "-pvc", Namespace: instance.Namespace}, pvc)
+		if errors.IsNotFound(err) {
+			return previous, nil
+		} else if err != nil {
+			return previous, err
+		}
+		if pvc.Spec.DataSource == nil {
+			return previous, nil
+		}
+		restore := &chainv1alpha1.RestoreStatus{
+			Source: pvc.Spec.DataSource.Kind + "/" + pvc.Spec.DataSource.Name,
+			Phase:  chainv1alpha1.RestorePending,
+		}
+		if pvc.Status.Phase == corev1.ClaimBound {
+			restore.Phase = chainv1alpha1.RestoreSucceeded
+			restore.Message = "Restored into " + pvc.Spec.VolumeName
+		} else {
+			restore.Message = "Waiting for the PVC to be provisioned"
+		}
+		return restore, nil
+	}
+
+	restore := &chainv1alpha1.RestoreStatus{
+		// The query may hold the signature of a presigned URL
+		Source: strings.SplitN(source.Archive.URL, "?", 2)[0],
+		Phase:  chainv1alpha1.RestorePending,
+	}
+	pod := &corev1.Pod{}
+	err := r.Get(ctx, types.NamespacedName{Name: name + "-0", Namespace: instance.Namespace}, pod)
+	if errors.IsNotFound(err) {
+		if previous != nil {
+			return previous, nil
+		}
+		return restore, nil
+	} else if err != nil {
+		return previous, err
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name != restoreContainerName {
+			continue
+		}
+		terminated := status.State.Terminated
+		if terminated == nil && status.State.Waiting != nil {
+			// Restarting after a failure
+			terminated = status.LastTerminationState.Terminated
+		}
+		if terminated == nil {
+			restore.Message = "Downloading and unpacking the archive"
+			return restore, nil
+		}
+		restore.Phase, restore.Message = restoreOutcome(terminated)
+		return restore, nil
+	}
+	return previous, nil
+}
+
+// restoreOutcome parses the termination message of the restore init container, e.g. "Failed: couldn't unpack the archive"
+func restoreOutcome(terminated *corev1.ContainerStateTerminated) (chainv1alpha1.RestorePhase, string) {
+	message := strings.TrimSpace(terminated.Message)
+	parts := strings.SplitN(message, ": ", 2)
+	if len(parts) == 2 {
+		switch phase := chainv1alpha1.RestorePhase(parts[0]); phase {
+		case chainv1alpha1.RestoreSucceeded, chainv1alpha1.RestoreSkipped, chainv1alpha1.RestoreFailed:
+			return phase, parts[1]
+		}
+	}
+	if terminated.ExitCode != 0 {
+		if message == "" {
+			message = terminated.Reason
+		}
+		return chainv1alpha1.RestoreFailed, message
+	}
+	return chainv1alpha1.RestoreSucceeded, message
+}
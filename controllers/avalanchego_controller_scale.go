@@ -0,0 +1,197 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+)
+
+const eventNodeRemoved = "NodeRemoved"
+
+// removeNodes removes the nodes with an index of nodeCount or higher, left over after nodeCount was lowered.
+// Nodes are removed one at a time, starting from the highest index.
+// Returns the number of nodes still to be removed.
+func (r *AvalanchegoReconciler) removeNodes(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) (int, error) {
+	highest, err := r.highestNodeIndex(ctx, instance)
+	if err != nil {
+		return 0, err
+	}
+	for i := highest; i >= instance.Spec.NodeCount; i-- {
+		removed, _, err := r.removeNode(ctx, instance, i, false, l)
+		if err != nil {
+			return i - instance.Spec.NodeCount + 1, err
+		}
+		if !removed {
+			return i - instance.Spec.NodeCount + 1, nil
+		}
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventNodeRemoved, "Removed node %d", i)
+	}
+	return 0, nil
+}
+
+// highestNodeIndex returns the highest index of a node StatefulSet or Service owned by the instance, or -1 if there are none
+func (r *AvalanchegoReconciler) highestNodeIndex(ctx context.Context, instance *chainv1alpha1.Avalanchego) (int, error) {
+	highest := -1
+	prefix := avaGoPrefix + instance.Spec.DeploymentName + "-"
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, client.InNamespace(instance.Namespace)); err != nil {
+		return highest, err
+	}
+	for i := range statefulSets.Items {
+		if index, ok := nodeIndex(instance, &statefulSets.Items[i], prefix, ""); ok && index > highest {
+			highest = index
+		}
+	}
+
+	services := &corev1.ServiceList{}
+	if err := r.List(ctx, services, client.InNamespace(instance.Namespace)); err != nil {
+		return highest, err
+	}
+	for i := range services.Items {
+		if index, ok := nodeIndex(instance, &services.Items[i], prefix, "-service"); ok && index > highest {
+			highest = index
+		}
+	}
+	return highest, nil
+}
+
+// nodeIndex parses the node index out of the name of an object controlled by the instance
+func nodeIndex(instance *chainv1alpha1.Avalanchego, obj metav1.Object, prefix string, suffix string) (int, bool) {
+	if !metav1.IsControlledBy(obj, instance) {
+		return 0, false
+	}
+	name := obj.GetName()
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return 0, false
+	}
+	index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix))
+	if err != nil {
+		return 0, false
+	}
+	return index, true
+}
+
+// removeNode deletes the objects of a single node: the StatefulSet first, the Service once the pod is gone,
+// then the PVC and the Secret, unless they are retained.
+// While scaling down, storageRetentionPolicy tells whether to keep the PVC and the Secret.
+// During teardown the PVC is snapshotted and archived if requested, and pre-defined secrets are deleted if adopted.
+// Returns true once the node is fully removed, otherwise what the removal is waiting for.
+func (r *AvalanchegoReconciler) removeNode(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	nodeId int,
+	teardown bool,
+	l logr.Logger,
+) (bool, string, error) {
+	name := avaGoPrefix + nodeBaseName(instance, nodeId)
+	l.Info("Removing node", "node", nodeId)
+
+	sts := &appsv1.StatefulSet{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: instance.Namespace}, sts)
+	switch {
+	case err == nil && sts.DeletionTimestamp == nil:
+		if err := r.Delete(ctx, sts); err != nil && !errors.IsNotFound(err) {
+			return false, "", err
+		}
+		return false, "waiting for StatefulSet " + name + " to be deleted", nil
+	case err == nil:
+		return false, "waiting for StatefulSet " + name + " to be deleted", nil
+	case !errors.IsNotFound(err):
+		return false, "", err
+	}
+
+	// Keeping the service until the node has stopped, so its peers can still reach it while it shuts down
+	err = r.Get(ctx, types.NamespacedName{Name: name + "-0", Namespace: instance.Namespace}, &corev1.Pod{})
+	if err == nil {
+		return false, "waiting for pod " + name + "-0 to stop", nil
+	} else if !errors.IsNotFound(err) {
+		return false, "", err
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name + "-pvc", Namespace: instance.Namespace}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name + "-key", Namespace: instance.Namespace}}
+	objects := []client.Object{
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name + "-service", Namespace: instance.Namespace}},
+	}
+	switch {
+	case teardown:
+		if instance.Spec.Teardown.Snapshot {
+			ready, err := r.ensureSnapshot(ctx, instance, pvc.Name, l)
+			if err != nil {
+				return false, "", err
+			}
+			if !ready {
+				return false, "waiting for the snapshot of PVC " + pvc.Name + " to be ready to use", nil
+			}
+		}
+		if instance.Spec.Teardown.ArchivePVCs {
+			if err := r.releaseObject(ctx, instance, pvc); err != nil {
+				return false, "", err
+			}
+		} else {
+			objects = append(objects, pvc)
+		}
+		switch {
+		case len(instance.Spec.ExistingSecrets) == 0:
+			objects = append(objects, secret)
+		case instance.Spec.AdoptSecrets && nodeId < len(instance.Spec.ExistingSecrets):
+			objects = append(objects,
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: instance.Spec.ExistingSecrets[nodeId], Namespace: instance.Namespace}},
+			)
+		}
+	case instance.Spec.StorageRetentionPolicy == chainv1alpha1.DeleteStorage:
+		objects = append(objects, pvc)
+		removeVolumeExpansion(instance, pvc.Name)
+		// Pre-defined secrets belong to the user, only the generated ones are deleted
+		if len(instance.Spec.ExistingSecrets) == 0 {
+			objects = append(objects, secret)
+		}
+		// Otherwise the key pair of a node added by raising nodeCount would be reused if it is raised again
+		if err := r.removeNetworkKeyPair(ctx, instance, nodeId); err != nil {
+			return false, "", err
+		}
+	}
+	for _, obj := range objects {
+		if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+			return false, "", err
+		}
+	}
+
+	instance.Status.NetworkMembersURI = removeS(instance.Status.NetworkMembersURI, name+"-service")
+	if err := r.Status().Update(ctx, instance); err != nil {
+		l.Error(err, "error calling NetworkMembersURI status update")
+		return false, "", err
+	}
+	return true, "", nil
+}
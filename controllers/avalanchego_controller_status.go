@@ -0,0 +1,390 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
+)
+
+// Reasons used in status conditions
+const (
+	reasonInvalidSpec         = "InvalidSpec"
+	reasonSecretsFailed       = "SecretsFailed"
+	reasonSecretsCreated      = "SecretsCreated"
+	reasonStatefulSetFailed   = "StatefulSetFailed"
+	reasonScaleDownFailed     = "ScaleDownFailed"
+	reasonBackupFailed        = "BackupFailed"
+	reasonUpgradeFailed       = "UpgradeFailed"
+	reasonUpgradePaused       = "UpgradePaused"
+	reasonReconcileSucceeded  = "ReconcileSucceeded"
+	reasonNodesNotReady       = "NodesNotReady"
+	reasonNodesUpdating       = "NodesUpdating"
+	reasonNodesRemoving       = "NodesRemoving"
+	reasonAllNodesReady       = "AllNodesReady"
+	reasonBootstrapperReady   = "BootstrapperReady"
+	reasonBootstrapperPending = "BootstrapperNotReady"
+	reasonExternalBootstrap   = "ExternalBootstrapper"
+)
+
+func setCondition(
+	instance *chainv1alpha1.Avalanchego,
+	conditionType string,
+	status metav1.ConditionStatus,
+	reason string,
+	message string,
+) {
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: instance.Generation,
+	})
+}
+
+// setErrorStatus records a failed reconciliation in status and returns the original error
+func (r *AvalanchegoReconciler) setErrorStatus(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	reason string,
+	err error,
+	l logr.Logger,
+) error {
+	instance.Status.Error = err.Error()
+	setCondition(instance, chainv1alpha1.ConditionDegraded, metav1.ConditionTrue, reason, err.Error())
+	setCondition(instance, chainv1alpha1.ConditionReady, metav1.ConditionFalse, reason, err.Error())
+	if reason == reasonSecretsFailed {
+		setCondition(instance, chainv1alpha1.ConditionSecretsReady, metav1.ConditionFalse, reason, err.Error())
+	}
+	instance.Status.Phase = chainv1alpha1.PhaseDegraded
+	instance.Status.ObservedGeneration = instance.Generation
+	if err := r.Status().Update(ctx, instance); err != nil {
+		l.Error(err, "error calling error status update")
+	}
+	return err
+}
+
+// Bounds of the requeue delay while waiting for nodes to become ready
+const (
+	minReadinessBackoff = 5 * time.Second
+	maxReadinessBackoff = 60 * time.Second
+)
+
+// Limits of the observation of the nodes in a reconciliation
+const (
+	// nodeProbeConcurrency is the number of nodes observed at the same time
+	nodeProbeConcurrency = 10
+	// nodeProbeDeadline bounds the calls to the node APIs, for all the nodes together
+	nodeProbeDeadline = 10 * time.Second
+)
+
+// setReadyStatus counts ready nodes and derives the conditions and the phase of a successfully reconciled instance.
+// genesis is the genesis of the nodes, if the operator has it, the initial stakers and their stakes are read from it.
+// outdated is the number of nodes still waiting for their StatefulSet to be updated,
+// removing is the number of nodes above nodeCount still to be removed.
+// Returns true if all the nodes are ready and up to date, and no node is left to remove.
+func (r *AvalanchegoReconciler) setReadyStatus(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	genesis string,
+	outdated int,
+	removing int,
+	l logr.Logger,
+) (bool, error) {
+	current, ready := 0, 0
+	readyBootstrappers := 0
+	var stakeEndTimes map[string]time.Time
+	instance.Status.GenesisStartTime = nil
+	if genesis != "" {
+		start, ends, err := common.GenesisStakeEndTimes(genesis)
+		if err != nil {
+			l.Error(err, "couldn't read initial stakers")
+		} else {
+			instance.Status.GenesisStartTime = &metav1.Time{Time: start}
+			stakeEndTimes = ends
+		}
+	}
+	nodes, found, err := r.observeNodes(ctx, instance, l)
+	if err != nil {
+		return false, err
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		if end, ok := stakeEndTimes[node.NodeID]; ok && node.NodeID != "" {
+			node.GenesisStaker = true
+			node.StakeEndTime = &metav1.Time{Time: end}
+		}
+		if !found[i] {
+			continue
+		}
+		current++
+		if node.Ready {
+			ready++
+			if node.Role == chainv1alpha1.NodeRoleBootstrapper {
+				readyBootstrappers++
+			}
+		}
+	}
+	instance.Status.Nodes = nodes
+	instance.Status.CurrentNodes = current
+	instance.Status.ReadyNodes = ready
+	allReady := ready == instance.Spec.NodeCount && outdated == 0 && removing == 0
+
+	instance.Status.Error = ""
+	setCondition(instance, chainv1alpha1.ConditionDegraded, metav1.ConditionFalse, reasonReconcileSucceeded, "")
+
+	// A quorum of the bootstrappers of this deployment has to be ready
+	switch bootstrapperNodes := bootstrapperNodes(instance); {
+	case instance.Spec.JoinsPublicNetwork():
+		setCondition(instance, chainv1alpha1.ConditionBootstrapped, metav1.ConditionTrue, reasonExternalBootstrap, "Nodes bootstrap from the beacons of "+string(instance.Spec.Network))
+	case len(bootstrapperNodes) == 0:
+		setCondition(instance, chainv1alpha1.ConditionBootstrapped, metav1.ConditionTrue, reasonExternalBootstrap, "Nodes bootstrap from "+instance.Status.BootstrapperURL)
+	case readyBootstrappers >= quorum(len(bootstrapperNodes)):
+		setCondition(instance, chainv1alpha1.ConditionBootstrapped, metav1.ConditionTrue, reasonBootstrapperReady, "")
+	default:
+		message := fmt.Sprintf("%d of %d bootstrappers are ready", readyBootstrappers, len(bootstrapperNodes))
+		setCondition(instance, chainv1alpha1.ConditionBootstrapped, metav1.ConditionFalse, reasonBootstrapperPending, message)
+	}
+
+	if allReady {
+		setCondition(instance, chainv1alpha1.ConditionProgressing, metav1.ConditionFalse, reasonAllNodesReady, "")
+		setCondition(instance, chainv1alpha1.ConditionReady, metav1.ConditionTrue, reasonAllNodesReady, "")
+		instance.Status.Phase = chainv1alpha1.PhaseRunning
+	} else if ready == instance.Spec.NodeCount && outdated == 0 {
+		message := fmt.Sprintf("%d nodes are waiting to be removed", removing)
+		setCondition(instance, chainv1alpha1.ConditionProgressing, metav1.ConditionTrue, reasonNodesRemoving, message)
+		setCondition(instance, chainv1alpha1.ConditionReady, metav1.ConditionFalse, reasonNodesRemoving, message)
+		instance.Status.Phase = chainv1alpha1.PhaseCreating
+	} else if upgrade := instance.Status.Upgrade; upgrade != nil && upgrade.Phase == chainv1alpha1.UpgradePaused && outdated > 0 {
+		message := fmt.Sprintf("Upgrade to %s paused, %d nodes are not upgraded: %s", upgrade.ToImage, outdated, upgrade.Message)
+		setCondition(instance, chainv1alpha1.ConditionProgressing, metav1.ConditionFalse, reasonUpgradePaused, message)
+		setCondition(instance, chainv1alpha1.ConditionReady, metav1.ConditionFalse, reasonUpgradePaused, message)
+		instance.Status.Phase = chainv1alpha1.PhaseCreating
+	} else if ready == instance.Spec.NodeCount {
+		message := fmt.Sprintf("%d of %d nodes are waiting to be updated", outdated, instance.Spec.NodeCount)
+		setCondition(instance, chainv1alpha1.ConditionProgressing, metav1.ConditionTrue, reasonNodesUpdating, message)
+		setCondition(instance, chainv1alpha1.ConditionReady, metav1.ConditionFalse, reasonNodesUpdating, message)
+		instance.Status.Phase = chainv1alpha1.PhaseCreating
+	} else {
+		message := fmt.Sprintf("%d of %d nodes are ready", ready, instance.Spec.NodeCount)
+		if outdated > 0 {
+			message += fmt.Sprintf(", %d waiting to be updated", outdated)
+		}
+		setCondition(instance, chainv1alpha1.ConditionProgressing, metav1.ConditionTrue, reasonNodesNotReady, message)
+		setCondition(instance, chainv1alpha1.ConditionReady, metav1.ConditionFalse, reasonNodesNotReady, message)
+		if current == 0 {
+			instance.Status.Phase = chainv1alpha1.PhasePending
+		} else {
+			instance.Status.Phase = chainv1alpha1.PhaseCreating
+		}
+	}
+	instance.Status.ObservedGeneration = instance.Generation
+
+	if err := r.Status().Update(ctx, instance); err != nil {
+		l.Error(err, "error calling ready status update")
+		return allReady, err
+	}
+	return allReady, nil
+}
+
+// isSettled returns true if the current spec of the instance has been reconciled successfully before.
+// Changes the operator makes to a settled instance correct drift, rather than roll out a new spec.
+func isSettled(instance *chainv1alpha1.Avalanchego) bool {
+	return instance.Status.ObservedGeneration == instance.Generation &&
+		meta.IsStatusConditionFalse(instance.Status.Conditions, chainv1alpha1.ConditionDegraded)
+}
+
+// readinessBackoff returns how long to wait before checking on nodes which are not ready yet.
+// The delay grows with the time the instance has been progressing, so stuck nodes are polled less often.
+func readinessBackoff(instance *chainv1alpha1.Avalanchego) time.Duration {
+	progressing := meta.FindStatusCondition(instance.Status.Conditions, chainv1alpha1.ConditionProgressing)
+	if progressing == nil || progressing.Status != metav1.ConditionTrue {
+		return minReadinessBackoff
+	}
+	backoff := time.Since(progressing.LastTransitionTime.Time) / 4
+	if backoff < minReadinessBackoff {
+		return minReadinessBackoff
+	}
+	if backoff > maxReadinessBackoff {
+		return maxReadinessBackoff
+	}
+	return backoff
+}
+
+// observeNodes observes all the nodes concurrently, so probing their APIs doesn't make the reconciliation grow with nodeCount.
+// Calls to the node APIs share a deadline: the nodes which don't answer in time are reported unhealthy.
+// Returns whether the StatefulSet of each node exists.
+func (r *AvalanchegoReconciler) observeNodes(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) ([]chainv1alpha1.NodeStatus, []bool, error) {
+	apiCtx, cancel := context.WithTimeout(ctx, nodeProbeDeadline)
+	defer cancel()
+
+	nodes := make([]chainv1alpha1.NodeStatus, instance.Spec.NodeCount)
+	found := make([]bool, instance.Spec.NodeCount)
+	errs := make([]error, instance.Spec.NodeCount)
+	sem := make(chan struct{}, nodeProbeConcurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < instance.Spec.NodeCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			nodes[i], found[i], errs[i] = r.observeNode(ctx, apiCtx, instance, i, l)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return nodes, found, nil
+}
+
+// observeNode builds the status of a single node from its StatefulSet, pod and staking certificate.
+// apiCtx bounds the calls to the API of the node.
+// Returns false if the StatefulSet of the node does not exist.
+func (r *AvalanchegoReconciler) observeNode(
+	ctx context.Context,
+	apiCtx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	nodeId int,
+	l logr.Logger,
+) (chainv1alpha1.NodeStatus, bool, error) {
+	name := avaGoPrefix + nodeBaseName(instance, nodeId)
+	node := chainv1alpha1.NodeStatus{
+		Index:       nodeId,
+		Role:        nodeRole(instance, nodeId),
+		ServiceName: name + "-service." + instance.Namespace + ".svc",
+	}
+	// Keeping the previous results, they are only replaced by successful observations
+	if nodeId < len(instance.Status.Nodes) && instance.Status.Nodes[nodeId].Index == nodeId {
+		node.NodeID = instance.Status.Nodes[nodeId].NodeID
+		node.Health = instance.Status.Nodes[nodeId].Health
+	}
+
+	restore, err := r.observeRestore(ctx, instance, nodeId)
+	if err != nil {
+		return node, false, err
+	}
+	node.Restore = restore
+
+	cert, err := r.nodeCertificate(ctx, instance, nodeId)
+	if err != nil {
+		l.Error(err, "couldn't read staking certificate", "node", nodeId)
+	} else if cert != "" {
+		if nodeID, err := common.NodeIDFromCert(cert); err != nil {
+			l.Error(err, "couldn't derive NodeID from staking certificate", "node", nodeId)
+		} else {
+			node.NodeID = nodeID
+		}
+	}
+
+	sts := &appsv1.StatefulSet{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: instance.Namespace}, sts)
+	if errors.IsNotFound(err) {
+		return node, false, nil
+	} else if err != nil {
+		return node, false, err
+	}
+	node.Ready = sts.Status.ReadyReplicas > 0
+
+	// A StatefulSet with a single replica always names its pod <name>-0
+	pod := &corev1.Pod{}
+	err = r.Get(ctx, types.NamespacedName{Name: name + "-0", Namespace: instance.Namespace}, pod)
+	if errors.IsNotFound(err) {
+		return node, true, nil
+	} else if err != nil {
+		return node, true, err
+	}
+	node.PodIP = pod.Status.PodIP
+	if node.PodIP == "" || r.NodeAPI == nil {
+		return node, true, nil
+	}
+
+	uri := "http://" + node.PodIP + ":9650"
+	health, err := r.NodeAPI.Health(apiCtx, uri)
+	if err != nil {
+		health = common.HealthResult{Healthy: false, Message: err.Error()}
+	}
+	// An unchanged result leaves the status as it is, so probing the node doesn't write the status every time
+	if previous := node.Health; previous == nil || previous.Healthy != health.Healthy || previous.Message != health.Message {
+		node.Health = &chainv1alpha1.NodeHealth{
+			Healthy:     health.Healthy,
+			Message:     health.Message,
+			LastChecked: metav1.Now(),
+		}
+	}
+	if hasBootstrappedGate(pod) {
+		bootstrapped, err := r.NodeAPI.Bootstrapped(apiCtx, uri, common.PrimaryNetworkChains...)
+		if err != nil {
+			l.Info("Couldn't check whether the node bootstrapped", "node", nodeId, "error", err.Error())
+		} else if err := r.setBootstrappedCondition(ctx, pod, bootstrapped); err != nil {
+			return node, true, err
+		}
+	}
+	// Nodes without a certificate generate their own, only the node itself knows its NodeID
+	if cert == "" && node.NodeID == "" {
+		if nodeID, err := r.NodeAPI.NodeID(apiCtx, uri); err == nil {
+			node.NodeID = nodeID
+		}
+	}
+	return node, true, nil
+}
+
+// nodeCertificate returns the PEM encoded staking certificate of the node, or an empty string if it has none
+func (r *AvalanchegoReconciler) nodeCertificate(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	nodeId int,
+) (string, error) {
+	if len(instance.Spec.Certificates) > nodeId {
+		cert, err := base64.StdEncoding.DecodeString(instance.Spec.Certificates[nodeId].Cert)
+		return string(cert), err
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      getSecretName(*instance, nodeId),
+		Namespace: instance.Namespace,
+	}, secret)
+	if errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return string(secret.Data["staker.crt"]), nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+)
+
+const (
+	eventVolumeAutoExpanded = "VolumeAutoExpanded"
+	eventVolumeFull         = "VolumeFull"
+
+	// diskUsageInterval is how often the disk usage is checked, when PVCs are expanded automatically
+	diskUsageInterval = time.Minute
+	// expansionCooldown is the minimum time between two expansions of a PVC
+	expansionCooldown = 5 * time.Minute
+)
+
+// autoExpandVolume grows the PVC of a node by a step, once its disk usage passes the threshold.
+// The new size is recorded in status.volumeExpansions, then applied by ensurePVC.
+// Only one expansion is made at a time: the PVC isn't grown again until the previous expansion completed.
+func (r *AvalanchegoReconciler) autoExpandVolume(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	nodeId int,
+	l logr.Logger,
+) error {
+	autoExpand := instance.Spec.Storage.AutoExpand
+	if autoExpand == nil || instance.Spec.Storage.EmptyDir || r.VolumeStats == nil {
+		return nil
+	}
+	name := avaGoPrefix + nodeBaseName(instance, nodeId)
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: name + "-pvc", Namespace: instance.Namespace}, pvc)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+	if !ok {
+		// Not bound yet
+		return nil
+	}
+	if capacity.Cmp(requested) < 0 {
+		l.Info("Waiting for PVC to be resized", "PVC", pvc.Name)
+		return nil
+	}
+	if expansion := volumeExpansion(instance, pvc.Name); expansion != nil {
+		if expansion.Size.Cmp(requested) > 0 {
+			// ensurePVC reports why the last expansion can't be applied
+			return nil
+		}
+		// The stats of the kubelet lag behind a resize
+		if time.Since(expansion.LastExpansionTime.Time) < expansionCooldown {
+			return nil
+		}
+	}
+
+	pod := &corev1.Pod{}
+	err = r.Get(ctx, types.NamespacedName{Name: name + "-0", Namespace: instance.Namespace}, pod)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if pod.Spec.NodeName == "" {
+		return nil
+	}
+	stats, err := r.VolumeStats.PVCStats(ctx, pod.Spec.NodeName, instance.Namespace, pvc.Name)
+	if err != nil {
+		// The PVC is checked again on the next reconciliation
+		l.Info("Couldn't get disk usage", "PVC", pvc.Name, "error", err.Error())
+		return nil
+	}
+	used := stats.UsedPercent()
+	threshold := autoExpand.ThresholdPercent
+	if threshold == 0 {
+		threshold = chainv1alpha1.DefaultAutoExpandThreshold
+	}
+	if used < threshold {
+		return nil
+	}
+
+	step := resource.MustParse(chainv1alpha1.DefaultAutoExpandStep)
+	if autoExpand.Step != nil {
+		step = *autoExpand.Step
+	}
+	size := requested.DeepCopy()
+	size.Add(step)
+	if size.Cmp(autoExpand.MaxSize) > 0 {
+		size = autoExpand.MaxSize.DeepCopy()
+	}
+	if size.Cmp(requested) <= 0 {
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventVolumeFull,
+			"PVC %s is %d%% full and already has the maximum size of %s", pvc.Name, used, autoExpand.MaxSize.String())
+		return nil
+	}
+
+	l.Info("Growing PVC", "PVC", pvc.Name, "used", used, "size", size.String())
+	setVolumeExpansion(instance, chainv1alpha1.VolumeExpansion{
+		PVC:               pvc.Name,
+		Size:              size,
+		UsedPercent:       used,
+		LastExpansionTime: metav1.Now(),
+	})
+	if err := r.Status().Update(ctx, instance); err != nil {
+		l.Error(err, "error calling VolumeExpansions status update")
+		return err
+	}
+	r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventVolumeAutoExpanded,
+		"PVC %s is %d%% full, growing it from %s to %s", pvc.Name, used, requested.String(), size.String())
+	return nil
+}
+
+// volumeExpansion returns the automatic expansions of a PVC recorded in status, or nil if there were none
+func volumeExpansion(instance *chainv1alpha1.Avalanchego, pvcName string) *chainv1alpha1.VolumeExpansion {
+	for i := range instance.Status.VolumeExpansions {
+		if instance.Status.VolumeExpansions[i].PVC == pvcName {
+			return &instance.Status.VolumeExpansions[i]
+		}
+	}
+	return nil
+}
+
+// setVolumeExpansion records a new expansion of a PVC in status
+func setVolumeExpansion(instance *chainv1alpha1.Avalanchego, expansion chainv1alpha1.VolumeExpansion) {
+	if existing := volumeExpansion(instance, expansion.PVC); existing != nil {
+		expansion.Count = existing.Count + 1
+		*existing = expansion
+		return
+	}
+	expansion.Count = 1
+	instance.Status.VolumeExpansions = append(instance.Status.VolumeExpansions, expansion)
+}
+
+// removeVolumeExpansion forgets the expansions of a deleted PVC, so a new PVC with the same name starts at the spec size
+func removeVolumeExpansion(instance *chainv1alpha1.Avalanchego, pvcName string) {
+	var expansions []chainv1alpha1.VolumeExpansion
+	for _, expansion := range instance.Status.VolumeExpansions {
+		if expansion.PVC != pvcName {
+			expansions = append(expansions, expansion)
+		}
+	}
+	instance.Status.VolumeExpansions = expansions
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+)
+
+// teardownFinalizer keeps a deleted Avalanchego object until its nodes are removed
+const teardownFinalizer = "chain.djtx.network/teardown"
+
+// VolumeSnapshots are handled as unstructured objects, so the operator doesn't depend on the external snapshotter
+var volumeSnapshotGVK = schema.GroupVersionKind{
+	Group:   "snapshot.storage.k8s.io",
+	Version: "v1",
+	Kind:    "VolumeSnapshot",
+}
+
+// teardown removes the nodes of a deleted instance, starting from the highest index, and then releases its finalizer.
+// Progress is recorded in status.teardown.
+func (r *AvalanchegoReconciler) teardown(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) (ctrl.Result, error) {
+	if instance.Status.Teardown == nil {
+		instance.Status.Teardown = &chainv1alpha1.TeardownStatus{
+			StartTime:      metav1.Now(),
+			LastUpdateTime: metav1.Now(),
+		}
+	}
+	instance.Status.Phase = chainv1alpha1.PhaseTerminating
+
+	highest, err := r.highestTeardownIndex(ctx, instance)
+	if err != nil {
+		return ctrl.Result{}, r.setTeardownStatus(ctx, instance, instance.Status.Teardown.CurrentNode, err.Error(), err, l)
+	}
+	for i := highest; i >= 0; i-- {
+		removed, waitingFor, err := r.removeNode(ctx, instance, i, true, l)
+		if err != nil {
+			return ctrl.Result{}, r.setTeardownStatus(ctx, instance, i, err.Error(), err, l)
+		}
+		if !removed {
+			if err := r.setTeardownStatus(ctx, instance, i, waitingFor, nil, l); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: minReadinessBackoff}, nil
+		}
+	}
+
+	l.Info("All nodes removed, releasing finalizer")
+	controllerutil.RemoveFinalizer(instance, teardownFinalizer)
+	if err := r.Update(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// setTeardownStatus records the progress of the teardown and returns the original error
+func (r *AvalanchegoReconciler) setTeardownStatus(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	nodeId int,
+	message string,
+	err error,
+	l logr.Logger,
+) error {
+	teardown := instance.Status.Teardown
+	if teardown.CurrentNode != nodeId || teardown.Message != message {
+		teardown.LastUpdateTime = metav1.Now()
+	}
+	teardown.CurrentNode = nodeId
+	teardown.Message = message
+	if err := r.Status().Update(ctx, instance); err != nil {
+		l.Error(err, "error calling teardown status update")
+		return err
+	}
+	return err
+}
+
+// highestTeardownIndex returns the highest index of a node with an object left to remove.
+// Unlike scale-down, PVCs are taken into account as well, they may be retained from an earlier scale-down.
+func (r *AvalanchegoReconciler) highestTeardownIndex(ctx context.Context, instance *chainv1alpha1.Avalanchego) (int, error) {
+	highest, err := r.highestNodeIndex(ctx, instance)
+	if err != nil {
+		return highest, err
+	}
+	if highest < instance.Spec.NodeCount-1 {
+		highest = instance.Spec.NodeCount - 1
+	}
+
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := r.List(ctx, pvcs, client.InNamespace(instance.Namespace)); err != nil {
+		return highest, err
+	}
+	prefix := avaGoPrefix + instance.Spec.DeploymentName + "-"
+	for i := range pvcs.Items {
+		if index, ok := nodeIndex(instance, &pvcs.Items[i], prefix, "-pvc"); ok && index > highest {
+			highest = index
+		}
+	}
+	return highest, nil
+}
+
+// ensureSnapshot takes a VolumeSnapshot of a PVC, unless it has been taken already.
+// Snapshots are not owned by the instance, so they outlive it.
+// Returns true once the snapshot is ready to use.
+func (r *AvalanchegoReconciler) ensureSnapshot(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	pvcName string,
+	l logr.Logger,
+) (bool, error) {
+	// The UID tells apart snapshots of objects re-created with the same name
+	name := pvcName + "-" + string(instance.UID)[:8]
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: instance.Namespace}, snapshot)
+	if errors.IsNotFound(err) {
+		found := &corev1.PersistentVolumeClaim{}
+		err := r.Get(ctx, types.NamespacedName{Name: pvcName, Namespace: instance.Namespace}, found)
+		if errors.IsNotFound(err) {
+			// Nothing to take a snapshot of
+			return true, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		snapshot = newVolumeSnapshot(name, found, instance.Spec.Teardown.VolumeSnapshotClassName)
+		l.Info("Taking snapshot", "PVC", pvcName, "VolumeSnapshot", name)
+		if err := r.Create(ctx, snapshot); err != nil {
+			return false, fmt.Errorf("couldn't create VolumeSnapshot %s: %w", name, err)
+		}
+		instance.Status.Teardown.Snapshots = append(instance.Status.Teardown.Snapshots, name)
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("couldn't get VolumeSnapshot %s: %w", name, err)
+	}
+
+	status := volumeSnapshotStatus(snapshot)
+	if status.Error != "" {
+		return false, fmt.Errorf("VolumeSnapshot %s failed: %s", name, status.Error)
+	}
+	return status.ReadyToUse, nil
+}
+
+// newVolumeSnapshot returns a VolumeSnapshot of a PVC, labelled like the PVC.
+// The default VolumeSnapshotClass is used if className is empty.
+func newVolumeSnapshot(name string, pvc *corev1.PersistentVolumeClaim, className string) *unstructured.Unstructured {
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	snapshot.SetName(name)
+	snapshot.SetNamespace(pvc.Namespace)
+	snapshot.SetLabels(pvc.Labels)
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": pvc.Name,
+		},
+	}
+	if className != "" {
+		spec["volumeSnapshotClassName"] = className
+	}
+	snapshot.Object["spec"] = spec
+	return snapshot
+}
+
+type snapshotStatus struct {
+	// Set once the snapshot is taken, the volume can be used again from then on
+	CreationTime *metav1.Time
+	ReadyToUse   bool
+	RestoreSize  *resource.Quantity
+	Error        string
+}
+
+// volumeSnapshotStatus reads the status of a VolumeSnapshot, missing fields are left empty
+func volumeSnapshotStatus(snapshot *unstructured.Unstructured) snapshotStatus {
+	var status snapshotStatus
+	if value, found, _ := unstructured.NestedString(snapshot.Object, "status", "creationTime"); found {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			creationTime := metav1.NewTime(t)
+			status.CreationTime = &creationTime
+		}
+	}
+	status.ReadyToUse, _, _ = unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+	if value, found, _ := unstructured.NestedString(snapshot.Object, "status", "restoreSize"); found {
+		if size, err := resource.ParseQuantity(value); err == nil {
+			status.RestoreSize = &size
+		}
+	}
+	status.Error, _, _ = unstructured.NestedString(snapshot.Object, "status", "error", "message")
+	return status
+}
+
+// releaseObject removes the owner reference to the instance from an object, so it isn't garbage collected with the instance
+func (r *AvalanchegoReconciler) releaseObject(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	obj client.Object,
+) error {
+	err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var refs []metav1.OwnerReference
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID != instance.UID {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == len(obj.GetOwnerReferences()) {
+		return nil
+	}
+	obj.SetOwnerReferences(refs)
+	return r.Update(ctx, obj)
+}
@@ -2,14 +2,27 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"time"
 
 	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ = Describe("Avalanchego controller", func() {
@@ -30,6 +43,18 @@ var _ = Describe("Avalanchego controller", func() {
 		interval = time.Millisecond * 500
 	)
 
+	// fetchGenesis returns the genesis.json of the genesis ConfigMap referenced from the status of an instance
+	fetchGenesis := func(fetched *chainv1alpha1.Avalanchego) string {
+		if fetched.Status.GenesisConfigMap == "" {
+			return ""
+		}
+		cm := &corev1.ConfigMap{}
+		if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: fetched.Status.GenesisConfigMap, Namespace: fetched.Namespace}, cm); err != nil {
+			return ""
+		}
+		return cm.Data["genesis.json"]
+	}
+
 	Context("Empty bootstrapperURL, genesis and certificates", func() {
 		It("Should handle new chain creation", func() {
 
@@ -81,7 +106,7 @@ var _ = Describe("Avalanchego controller", func() {
 
 			By("Checking, if genesis was generated")
 
-			Expect(fetched.Status.Genesis).ShouldNot(Equal(""))
+			Expect(fetchGenesis(fetched)).ShouldNot(Equal(""))
 
 			By("Deleting the scope")
 			Eventually(func() error {
@@ -527,4 +552,2384 @@ var _ = Describe("Avalanchego controller", func() {
 			}, timeout, interval).ShouldNot(Succeed())
 		})
 	})
+
+	Context("Reserved environment variables", func() {
+		It("Should ignore all of them", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-reserved-env",
+				NodeCount:      1,
+				Env: []corev1.EnvVar{
+					{
+						Name:  "AVAGO_PUBLIC_IP",
+						Value: "1.2.3.4",
+					},
+					{
+						Name:  "AVAGO_DB_DIR",
+						Value: "/tmp",
+					},
+					{
+						Name:  "AVAGO_LOG_LEVEL",
+						Value: "debug",
+					},
+				},
+				ExistingSecrets: []string{
+					"test-secret-1",
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-reserved-env",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking StatefulSet environment")
+			sts := &appsv1.StatefulSet{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{
+					Name:      "avago-test-reserved-env-0",
+					Namespace: AvalanchegoNamespace,
+				}, sts)
+			}, timeout, interval).Should(Succeed())
+
+			env := sts.Spec.Template.Spec.Containers[0].Env
+			Expect(env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_LOG_LEVEL", Value: "debug"}))
+			Expect(env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_DB_DIR", Value: "/root/.avalanchego"}))
+			Expect(env).ShouldNot(ContainElement(corev1.EnvVar{Name: "AVAGO_DB_DIR", Value: "/tmp"}))
+			for _, v := range env {
+				Expect(v.Value).ShouldNot(Equal("1.2.3.4"))
+			}
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Status conditions", func() {
+		It("Should report conditions, phase and observed generation", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-conditions",
+				NodeCount:      2,
+				ExistingSecrets: []string{
+					"test-secret-1",
+					"test-secret-2",
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-conditions",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() bool {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.ObservedGeneration == fetched.Generation &&
+					meta.IsStatusConditionTrue(fetched.Status.Conditions, chainv1alpha1.ConditionSecretsReady)
+			}, timeout, interval).Should(BeTrue())
+
+			By("Checking that nodes without pods are not ready")
+			// testEnv does not run pods, so StatefulSets never become ready
+			Expect(meta.IsStatusConditionFalse(fetched.Status.Conditions, chainv1alpha1.ConditionReady)).Should(BeTrue())
+			Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, chainv1alpha1.ConditionProgressing)).Should(BeTrue())
+			Expect(meta.IsStatusConditionFalse(fetched.Status.Conditions, chainv1alpha1.ConditionDegraded)).Should(BeTrue())
+			Expect(fetched.Status.Phase).Should(Equal(chainv1alpha1.PhaseCreating))
+			// The second node waits for the first one to become ready
+			Expect(fetched.Status.CurrentNodes).Should(Equal(1))
+			Expect(fetched.Status.ReadyNodes).Should(Equal(0))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Node status", func() {
+		It("Should derive NodeIDs from pre-defined secrets", func() {
+			genesis, err := common.RenderGenesis(&chainv1alpha1.AvalanchegoSpec{NodeCount: 1}, time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			network, err := common.NewNetwork(genesis)
+			Expect(err).NotTo(HaveOccurred())
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-node-status-secret",
+					Namespace: AvalanchegoNamespace,
+				},
+				StringData: map[string]string{
+					"staker.crt":   network.KeyPairs[0].Cert,
+					"staker.key":   network.KeyPairs[0].Key,
+					"genesis.json": network.Genesis,
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), secret)).Should(Succeed())
+
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:             "v1.6.3",
+				DeploymentName:  "test-node-status",
+				NodeCount:       1,
+				ExistingSecrets: []string{secret.Name},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-node-status",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() int {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return len(fetched.Status.Nodes)
+			}, timeout, interval).Should(Equal(1))
+
+			node := fetched.Status.Nodes[0]
+			Expect(node.Index).Should(Equal(0))
+			Expect(node.NodeID).Should(Equal(network.KeyPairs[0].Id))
+			Expect(node.ServiceName).Should(Equal("avago-test-node-status-0-service." + AvalanchegoNamespace + ".svc"))
+			Expect(node.Ready).Should(BeFalse())
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+			Expect(k8sClient.Delete(context.Background(), secret)).Should(Succeed())
+		})
+	})
+
+	Context("Rolling updates", func() {
+		It("Should update nodes one at a time", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-rolling",
+				NodeCount:      2,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-rolling",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking that all the nodes of a new network are created at once")
+			for _, name := range []string{"avago-test-rolling-0", "avago-test-rolling-1"} {
+				stsKey := types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}
+				Eventually(func() error {
+					return k8sClient.Get(context.Background(), stsKey, &appsv1.StatefulSet{})
+				}, timeout, interval).Should(Succeed())
+			}
+
+			By("Changing the image tag")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				f.Spec.Tag = "v1.6.4"
+				return k8sClient.Update(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			image := func(name string) func() string {
+				return func() string {
+					sts := &appsv1.StatefulSet{}
+					_ = k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}, sts)
+					if len(sts.Spec.Template.Spec.Containers) == 0 {
+						return ""
+					}
+					return sts.Spec.Template.Spec.Containers[0].Image
+				}
+			}
+			Eventually(image("avago-test-rolling-0"), timeout, interval).Should(HaveSuffix(":v1.6.4"))
+
+			By("Checking that the second node waits for the first one to become ready")
+			// testEnv does not run pods, so the first node never becomes ready
+			Consistently(image("avago-test-rolling-1"), time.Second, interval).Should(HaveSuffix(":v1.6.3"))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Drift correction", func() {
+		It("Should recreate deleted node services", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-drift",
+				NodeCount:      1,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-drift",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() bool {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.ObservedGeneration == fetched.Generation &&
+					meta.IsStatusConditionFalse(fetched.Status.Conditions, chainv1alpha1.ConditionDegraded)
+			}, timeout, interval).Should(BeTrue())
+
+			svcKey := types.NamespacedName{Name: "avago-test-drift-0-service", Namespace: AvalanchegoNamespace}
+			svc := &corev1.Service{}
+			Expect(k8sClient.Get(context.Background(), svcKey, svc)).Should(Succeed())
+
+			By("Deleting the node service")
+			Expect(k8sClient.Delete(context.Background(), svc)).Should(Succeed())
+
+			By("Checking that the service is recreated")
+			Eventually(func() types.UID {
+				recreated := &corev1.Service{}
+				_ = k8sClient.Get(context.Background(), svcKey, recreated)
+				return recreated.UID
+			}, timeout, interval).ShouldNot(Or(BeEmpty(), Equal(svc.UID)))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Scale-down", func() {
+		It("Should remove the highest-index nodes", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:                    "v1.6.3",
+				DeploymentName:         "test-scale-down",
+				NodeCount:              2,
+				StorageRetentionPolicy: chainv1alpha1.DeleteStorage,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-scale-down",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			stsKey := types.NamespacedName{Name: "avago-test-scale-down-1", Namespace: AvalanchegoNamespace}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), stsKey, &appsv1.StatefulSet{})
+			}, timeout, interval).Should(Succeed())
+
+			By("Lowering nodeCount")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				f.Spec.NodeCount = 1
+				return k8sClient.Update(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			By("Checking that the second node is removed")
+			Eventually(func() bool {
+				svc := types.NamespacedName{Name: "avago-test-scale-down-1-service", Namespace: AvalanchegoNamespace}
+				secret := types.NamespacedName{Name: "avago-test-scale-down-1-key", Namespace: AvalanchegoNamespace}
+				return errors.IsNotFound(k8sClient.Get(context.Background(), stsKey, &appsv1.StatefulSet{})) &&
+					errors.IsNotFound(k8sClient.Get(context.Background(), svc, &corev1.Service{})) &&
+					errors.IsNotFound(k8sClient.Get(context.Background(), secret, &corev1.Secret{}))
+			}, timeout, interval).Should(BeTrue())
+
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() []string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.NetworkMembersURI
+			}, timeout, interval).Should(Equal([]string{"avago-test-scale-down-0-service"}))
+
+			By("Checking that the first node is kept")
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-scale-down-0", Namespace: AvalanchegoNamespace}, &appsv1.StatefulSet{})).Should(Succeed())
+
+			By("Checking that the key pair of the removed genesis staker is kept")
+			network := &corev1.Secret{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-scale-down-network", Namespace: AvalanchegoNamespace}, network)).Should(Succeed())
+			Expect(network.Data).Should(HaveKey("staker-1.crt"))
+			Consistently(func() bool {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return meta.IsStatusConditionTrue(fetched.Status.Conditions, chainv1alpha1.ConditionSecretsReady)
+			}, 5*time.Second, interval).Should(BeTrue())
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Scale-up", func() {
+		It("Should issue staking keys for added nodes of a generated network", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-scale-up",
+				NodeCount:      1,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-scale-up",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetchGenesis(fetched)
+			}, timeout, interval).ShouldNot(BeEmpty())
+
+			By("Raising nodeCount")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				f.Spec.NodeCount = 2
+				return k8sClient.Update(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			By("Checking that the added node has a staking key")
+			secret := &corev1.Secret{}
+			Eventually(func() []byte {
+				_ = k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-scale-up-1-key", Namespace: AvalanchegoNamespace}, secret)
+				return secret.Data["staker.crt"]
+			}, timeout, interval).ShouldNot(BeEmpty())
+			Expect(secret.Data).ShouldNot(HaveKey("genesis.json"))
+
+			By("Checking that only the first node is a genesis staker")
+			Eventually(func() int {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return len(fetched.Status.Nodes)
+			}, timeout, interval).Should(Equal(2))
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.Nodes[1].NodeID
+			}, timeout, interval).ShouldNot(BeEmpty())
+			Expect(fetched.Status.Nodes[0].GenesisStaker).Should(BeTrue())
+			Expect(fetched.Status.Nodes[1].GenesisStaker).Should(BeFalse())
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Network secret", func() {
+		It("Should store the generated network and restore node secrets from it", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-network-secret",
+				NodeCount:      2,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-network-secret",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() bool {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetchGenesis(fetched) != "" &&
+					meta.IsStatusConditionTrue(fetched.Status.Conditions, chainv1alpha1.ConditionSecretsReady)
+			}, timeout, interval).Should(BeTrue())
+
+			By("Checking the network secret")
+			network := &corev1.Secret{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-network-secret-network", Namespace: AvalanchegoNamespace}, network)).Should(Succeed())
+			Expect(string(network.Data["genesis.json"])).Should(Equal(fetchGenesis(fetched)))
+			Expect(network.Data).Should(HaveKey("staker-0.crt"))
+			Expect(network.Data).Should(HaveKey("staker-1.key"))
+
+			By("Deleting a node secret")
+			secretKey := types.NamespacedName{Name: "avago-test-network-secret-1-key", Namespace: AvalanchegoNamespace}
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(context.Background(), secretKey, secret)).Should(Succeed())
+			Expect(k8sClient.Delete(context.Background(), secret)).Should(Succeed())
+
+			By("Checking that it is restored with the same key pair")
+			Eventually(func() []byte {
+				restored := &corev1.Secret{}
+				_ = k8sClient.Get(context.Background(), secretKey, restored)
+				return restored.Data["staker.crt"]
+			}, timeout, interval).Should(Equal(network.Data["staker-1.crt"]))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+
+		It("Should rebuild a lost network secret instead of generating a new network", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-lost-network",
+				NodeCount:      2,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-lost-network",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() bool {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetchGenesis(fetched) != "" &&
+					meta.IsStatusConditionTrue(fetched.Status.Conditions, chainv1alpha1.ConditionSecretsReady)
+			}, timeout, interval).Should(BeTrue())
+			genesisConfigMap := fetched.Status.GenesisConfigMap
+			nodeIDs := func() []string {
+				var ids []string
+				for i := 0; i < 2; i++ {
+					secret := &corev1.Secret{}
+					_ = k8sClient.Get(context.Background(), types.NamespacedName{Name: fmt.Sprintf("avago-test-lost-network-%d-key", i), Namespace: AvalanchegoNamespace}, secret)
+					id, _ := common.NodeIDFromCert(string(secret.Data["staker.crt"]))
+					ids = append(ids, id)
+				}
+				return ids
+			}
+			before := nodeIDs()
+			Expect(before).ShouldNot(ContainElement(""))
+
+			By("Deleting the network secret")
+			networkKey := types.NamespacedName{Name: "avago-test-lost-network-network", Namespace: AvalanchegoNamespace}
+			network := &corev1.Secret{}
+			Expect(k8sClient.Get(context.Background(), networkKey, network)).Should(Succeed())
+			Expect(k8sClient.Delete(context.Background(), network)).Should(Succeed())
+
+			By("Checking that it is rebuilt with the same genesis and key pairs")
+			rebuilt := &corev1.Secret{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), networkKey, rebuilt)
+				return err == nil && rebuilt.UID != network.UID
+			}, timeout, interval).Should(BeTrue())
+			Expect(rebuilt.Data["genesis.json"]).Should(Equal(network.Data["genesis.json"]))
+			Expect(rebuilt.Data["staker-0.crt"]).Should(Equal(network.Data["staker-0.crt"]))
+			Expect(rebuilt.Data["staker-1.crt"]).Should(Equal(network.Data["staker-1.crt"]))
+			Consistently(nodeIDs, time.Second, interval).Should(Equal(before))
+			Expect(k8sClient.Get(context.Background(), key, fetched)).Should(Succeed())
+			Expect(fetched.Status.GenesisConfigMap).Should(Equal(genesisConfigMap))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Teardown", func() {
+		It("Should remove the nodes and archive their data", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:             "v1.6.3",
+				DeploymentName:  "test-teardown",
+				NodeCount:       2,
+				ExistingSecrets: []string{"test-teardown-secret-1", "test-teardown-secret-2"},
+				AdoptSecrets:    true,
+				Teardown: chainv1alpha1.TeardownSpec{
+					ArchivePVCs: true,
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-teardown",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			for _, name := range spec.ExistingSecrets {
+				Expect(k8sClient.Create(context.Background(), &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: AvalanchegoNamespace,
+					},
+				})).Should(Succeed())
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking that the finalizer is added")
+			Eventually(func() []string {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return f.Finalizers
+			}, timeout, interval).Should(ContainElement("chain.djtx.network/teardown"))
+
+			pvcKey := types.NamespacedName{Name: "avago-test-teardown-1-pvc", Namespace: AvalanchegoNamespace}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), pvcKey, &corev1.PersistentVolumeClaim{})
+			}, timeout, interval).Should(Succeed())
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+
+			By("Checking that the nodes are removed")
+			for _, name := range []string{"avago-test-teardown-0", "avago-test-teardown-1"} {
+				stsKey := types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}
+				Expect(errors.IsNotFound(k8sClient.Get(context.Background(), stsKey, &appsv1.StatefulSet{}))).Should(BeTrue())
+			}
+
+			By("Checking that the adopted secrets are deleted")
+			for _, name := range spec.ExistingSecrets {
+				secretKey := types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}
+				Expect(errors.IsNotFound(k8sClient.Get(context.Background(), secretKey, &corev1.Secret{}))).Should(BeTrue())
+			}
+
+			By("Checking that the PVCs are archived")
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(k8sClient.Get(context.Background(), pvcKey, pvc)).Should(Succeed())
+			Expect(pvc.OwnerReferences).Should(BeEmpty())
+		})
+	})
+
+	Context("Storage", func() {
+		It("Should create PVCs from the storage spec", func() {
+			size := resource.MustParse("10Gi")
+			storageClassName := "test-storage"
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-storage",
+				NodeCount:      1,
+				Storage: chainv1alpha1.StorageSpec{
+					Size:             &size,
+					StorageClassName: &storageClassName,
+					AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-storage",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking the PVC")
+			pvc := &corev1.PersistentVolumeClaim{}
+			pvcKey := types.NamespacedName{Name: "avago-test-storage-0-pvc", Namespace: AvalanchegoNamespace}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), pvcKey, pvc)
+			}, timeout, interval).Should(Succeed())
+			Expect(pvc.Spec.StorageClassName).Should(Equal(&storageClassName))
+			Expect(pvc.Spec.AccessModes).Should(Equal([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}))
+			Expect(pvc.Spec.Resources.Requests.Storage().Equal(size)).Should(BeTrue())
+
+			By("Raising the size over what the storage class allows")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				larger := resource.MustParse("20Gi")
+				f.Spec.Storage.Size = &larger
+				return k8sClient.Update(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+			// The size that can't be applied is recorded once, instead of warning on every reconciliation
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), pvcKey, pvc)
+				return pvc.Annotations["chain.djtx.network/unexpandable-size"]
+			}, timeout, interval).Should(Equal("20Gi"))
+			Expect(pvc.Spec.Resources.Requests.Storage().Equal(size)).Should(BeTrue())
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Automatic PVC expansion", func() {
+		It("Should grow a PVC past the disk usage threshold", func() {
+			size := resource.MustParse("10Gi")
+			step := resource.MustParse("5Gi")
+			storageClassName := "test-auto-expand"
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-auto-expand",
+				NodeCount:      1,
+				Storage: chainv1alpha1.StorageSpec{
+					Size:             &size,
+					StorageClassName: &storageClassName,
+					AutoExpand: &chainv1alpha1.AutoExpandSpec{
+						ThresholdPercent: 80,
+						Step:             &step,
+						MaxSize:          resource.MustParse("20Gi"),
+					},
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-auto-expand",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			allowVolumeExpansion := true
+			Expect(k8sClient.Create(context.Background(), &storagev1.StorageClass{
+				ObjectMeta:           metav1.ObjectMeta{Name: storageClassName},
+				Provisioner:          "test-provisioner",
+				AllowVolumeExpansion: &allowVolumeExpansion,
+			})).Should(Succeed())
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Binding the PVC and scheduling the pod")
+			pvcKey := types.NamespacedName{Name: "avago-test-auto-expand-0-pvc", Namespace: AvalanchegoNamespace}
+			Eventually(func() error {
+				pvc := &corev1.PersistentVolumeClaim{}
+				if err := k8sClient.Get(context.Background(), pvcKey, pvc); err != nil {
+					return err
+				}
+				pvc.Status.Phase = corev1.ClaimBound
+				pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: size}
+				return k8sClient.Status().Update(context.Background(), pvc)
+			}, timeout, interval).Should(Succeed())
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "avago-test-auto-expand-0-0",
+					Namespace: AvalanchegoNamespace,
+				},
+				Spec: corev1.PodSpec{
+					NodeName:   "test-node",
+					Containers: []corev1.Container{{Name: "avago", Image: "avaplatform/avalanchego:v1.6.3"}},
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), pod)).Should(Succeed())
+
+			By("Filling up the disk")
+			volumeStats.Set(AvalanchegoNamespace, pvcKey.Name, common.VolumeStats{CapacityBytes: 100, UsedBytes: 90})
+
+			By("Checking that the expansion is recorded and applied")
+			Eventually(func() []chainv1alpha1.VolumeExpansion {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return f.Status.VolumeExpansions
+			}, timeout, interval).Should(HaveLen(1))
+
+			Eventually(func() string {
+				pvc := &corev1.PersistentVolumeClaim{}
+				_ = k8sClient.Get(context.Background(), pvcKey, pvc)
+				return pvc.Spec.Resources.Requests.Storage().String()
+			}, timeout, interval).Should(Equal("15Gi"))
+
+			By("Deleting the scope")
+			Expect(k8sClient.Delete(context.Background(), pod, client.GracePeriodSeconds(0))).Should(Succeed())
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Backups", func() {
+		It("Should take scheduled backups and keep spec.backup.keep of them", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-backup",
+				NodeCount:      1,
+				Backup: &chainv1alpha1.BackupSpec{
+					Schedule: "0 3 * * *",
+					Keep:     1,
+					StopNode: true,
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-backup",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+			// reconcileAt moves the clock of the reconciler and has the instance reconciled at that time
+			reconcileAt := func(t time.Time) {
+				testClock.SetTime(t)
+				Eventually(func() error {
+					f := &chainv1alpha1.Avalanchego{}
+					if err := k8sClient.Get(context.Background(), key, f); err != nil {
+						return err
+					}
+					if f.Annotations == nil {
+						f.Annotations = map[string]string{}
+					}
+					f.Annotations["test/reconciled-at"] = t.Format(time.RFC3339)
+					return k8sClient.Update(context.Background(), f)
+				}, timeout, interval).Should(Succeed())
+			}
+			// takeSnapshot stands in for the external snapshotter
+			takeSnapshot := func(name string) {
+				Eventually(func() error {
+					snapshot := &unstructured.Unstructured{}
+					snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+					if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}, snapshot); err != nil {
+						return err
+					}
+					snapshot.Object["status"] = map[string]interface{}{
+						"creationTime": time.Now().UTC().Format(time.RFC3339),
+						"readyToUse":   true,
+						"restoreSize":  "10Gi",
+					}
+					return k8sClient.Status().Update(context.Background(), snapshot)
+				}, timeout, interval).Should(Succeed())
+			}
+			replicas := func() int32 {
+				sts := &appsv1.StatefulSet{}
+				_ = k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-backup-0", Namespace: AvalanchegoNamespace}, sts)
+				if sts.Spec.Replicas == nil {
+					return -1
+				}
+				return *sts.Spec.Replicas
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+			Eventually(replicas, timeout, interval).Should(Equal(int32(1)))
+
+			By("Checking that no backup is taken before the schedule")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Expect(k8sClient.Get(context.Background(), key, fetched)).Should(Succeed())
+			created := fetched.CreationTimestamp.Time
+			reconcileAt(created)
+			Consistently(func() []chainv1alpha1.Backup {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.Backups
+			}, time.Second*2, interval).Should(BeEmpty())
+
+			By("Checking that the node is stopped while it is backed up")
+			first := created.Add(25 * time.Hour)
+			reconcileAt(first)
+			Eventually(func() []chainv1alpha1.Backup {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.Backups
+			}, timeout, interval).Should(HaveLen(1))
+			Expect(fetched.Status.LastBackupTime.Time.Equal(first.Truncate(time.Second))).Should(BeTrue())
+			Expect(fetched.Status.Backups[0].Node).Should(Equal(0))
+			firstName := fetched.Status.Backups[0].Name
+			Expect(firstName).Should(Equal("avago-test-backup-0-pvc-" + first.UTC().Format("20060102-150405")))
+			Eventually(replicas, timeout, interval).Should(Equal(int32(0)))
+
+			By("Checking that the backup completes and the node restarts once the snapshot is taken")
+			takeSnapshot(firstName)
+			Eventually(func() bool {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.Backups[0].ReadyToUse
+			}, timeout, interval).Should(BeTrue())
+			Expect(fetched.Status.Backups[0].Error).Should(BeEmpty())
+			Expect(fetched.Status.Backups[0].SnapshotTime).ShouldNot(BeNil())
+			Expect(fetched.Status.Backups[0].Size.String()).Should(Equal("10Gi"))
+			Eventually(replicas, timeout, interval).Should(Equal(int32(1)))
+
+			By("Checking that the oldest backup is deleted past spec.backup.keep")
+			reconcileAt(first.Add(24 * time.Hour))
+			Eventually(func() []chainv1alpha1.Backup {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.Backups
+			}, timeout, interval).Should(HaveLen(2))
+			secondName := fetched.Status.Backups[1].Name
+			takeSnapshot(secondName)
+			Eventually(func() []string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				names := []string{}
+				for _, b := range fetched.Status.Backups {
+					names = append(names, b.Name)
+				}
+				return names
+			}, timeout, interval).Should(Equal([]string{secondName}))
+			Eventually(func() bool {
+				snapshot := &unstructured.Unstructured{}
+				snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+				err := k8sClient.Get(context.Background(), types.NamespacedName{Name: firstName, Namespace: AvalanchegoNamespace}, snapshot)
+				return errors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+
+		It("Should not count failed backups toward spec.backup.keep", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-failed-backup",
+				NodeCount:      1,
+				Backup: &chainv1alpha1.BackupSpec{
+					Schedule: "0 3 * * *",
+					Keep:     1,
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-failed-backup",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+			reconcileAt := func(t time.Time) {
+				testClock.SetTime(t)
+				Eventually(func() error {
+					f := &chainv1alpha1.Avalanchego{}
+					if err := k8sClient.Get(context.Background(), key, f); err != nil {
+						return err
+					}
+					if f.Annotations == nil {
+						f.Annotations = map[string]string{}
+					}
+					f.Annotations["test/reconciled-at"] = t.Format(time.RFC3339)
+					return k8sClient.Update(context.Background(), f)
+				}, timeout, interval).Should(Succeed())
+			}
+			// setSnapshotStatus stands in for the external snapshotter
+			setSnapshotStatus := func(name string, status map[string]interface{}) {
+				Eventually(func() error {
+					snapshot := &unstructured.Unstructured{}
+					snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+					if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}, snapshot); err != nil {
+						return err
+					}
+					snapshot.Object["status"] = status
+					return k8sClient.Status().Update(context.Background(), snapshot)
+				}, timeout, interval).Should(Succeed())
+			}
+			snapshotExists := func(name string) bool {
+				snapshot := &unstructured.Unstructured{}
+				snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+				err := k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}, snapshot)
+				return !errors.IsNotFound(err)
+			}
+			fetched := &chainv1alpha1.Avalanchego{}
+			backups := func() []chainv1alpha1.Backup {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.Backups
+			}
+			// runBackup takes the backup scheduled at t, and returns its name once its snapshot has the given status
+			runBackup := func(t time.Time, status map[string]interface{}) string {
+				reconcileAt(t)
+				Eventually(func() string {
+					for _, b := range backups() {
+						if b.StartTime.Time.Equal(t.Truncate(time.Second)) {
+							return b.Name
+						}
+					}
+					return ""
+				}, timeout, interval).ShouldNot(BeEmpty())
+				name := "avago-test-failed-backup-0-pvc-" + t.UTC().Format("20060102-150405")
+				setSnapshotStatus(name, status)
+				reconcileAt(t.Add(time.Minute))
+				return name
+			}
+			failed := map[string]interface{}{"error": map[string]interface{}{"message": "snapshot class not found"}}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-failed-backup-0-pvc", Namespace: AvalanchegoNamespace}, &corev1.PersistentVolumeClaim{})
+			}, timeout, interval).Should(Succeed())
+			Expect(k8sClient.Get(context.Background(), key, fetched)).Should(Succeed())
+			first := fetched.CreationTimestamp.Time.Add(25 * time.Hour)
+
+			By("Taking a successful backup")
+			good := runBackup(first, map[string]interface{}{
+				"creationTime": time.Now().UTC().Format(time.RFC3339),
+				"readyToUse":   true,
+				"restoreSize":  "10Gi",
+			})
+			Eventually(func() bool {
+				b := backups()
+				return len(b) == 1 && b[0].ReadyToUse
+			}, timeout, interval).Should(BeTrue())
+
+			By("Failing the next backups")
+			second := runBackup(first.Add(24*time.Hour), failed)
+			Eventually(func() string {
+				b := backups()
+				return b[len(b)-1].Error
+			}, timeout, interval).Should(Equal("snapshot class not found"))
+			third := runBackup(first.Add(48*time.Hour), failed)
+
+			By("Checking that the good backup is kept, along with the latest failure only")
+			Eventually(func() []string {
+				names := []string{}
+				for _, b := range backups() {
+					names = append(names, b.Name)
+				}
+				return names
+			}, timeout, interval).Should(Equal([]string{good, third}))
+			Expect(fetched.Status.Backups[0].ReadyToUse).Should(BeTrue())
+			Expect(fetched.Status.Backups[1].Error).Should(Equal("snapshot class not found"))
+			Expect(snapshotExists(good)).Should(BeTrue())
+			Eventually(func() bool {
+				return snapshotExists(second)
+			}, timeout, interval).Should(BeFalse())
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Data sources", func() {
+		It("Should clone new PVCs from spec.dataSource", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-clone",
+				NodeCount:      1,
+				DataSource: &chainv1alpha1.DataSourceSpec{
+					PersistentVolumeClaim: "avago-seed",
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-clone",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking that the PVC is cloned")
+			pvc := &corev1.PersistentVolumeClaim{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-clone-0-pvc", Namespace: AvalanchegoNamespace}, pvc)
+			}, timeout, interval).Should(Succeed())
+			Expect(pvc.Spec.DataSource).ShouldNot(BeNil())
+			Expect(pvc.Spec.DataSource.Kind).Should(Equal("PersistentVolumeClaim"))
+			Expect(pvc.Spec.DataSource.Name).Should(Equal("avago-seed"))
+
+			By("Checking that the restore is pending until the PVC is bound")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() *chainv1alpha1.RestoreStatus {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				if len(fetched.Status.Nodes) == 0 {
+					return nil
+				}
+				return fetched.Status.Nodes[0].Restore
+			}, timeout, interval).Should(Equal(&chainv1alpha1.RestoreStatus{
+				Source:  "PersistentVolumeClaim/avago-seed",
+				Phase:   chainv1alpha1.RestorePending,
+				Message: "Waiting for the PVC to be provisioned",
+			}))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+
+		It("Should unpack an archive before the node starts", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-archive",
+				NodeCount:      1,
+				DataSource: &chainv1alpha1.DataSourceSpec{
+					Archive: &chainv1alpha1.ArchiveSource{
+						URL:    "https://snapshots.example.com/db.tar.gz?X-Amz-Signature=secret",
+						SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+					},
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-archive",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking that the archive is unpacked by the first init container")
+			sts := &appsv1.StatefulSet{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-archive-0", Namespace: AvalanchegoNamespace}, sts)
+			}, timeout, interval).Should(Succeed())
+			initContainers := sts.Spec.Template.Spec.InitContainers
+			Expect(initContainers).ShouldNot(BeEmpty())
+			Expect(initContainers[0].Name).Should(Equal("restore-db"))
+			Expect(initContainers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "ARCHIVE_URL", Value: spec.DataSource.Archive.URL}))
+			Expect(initContainers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "ARCHIVE_SHA256", Value: spec.DataSource.Archive.SHA256}))
+
+			By("Checking that the signature of the URL is kept out of status")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				if len(fetched.Status.Nodes) == 0 || fetched.Status.Nodes[0].Restore == nil {
+					return ""
+				}
+				return fetched.Status.Nodes[0].Restore.Source
+			}, timeout, interval).Should(Equal("https://snapshots.example.com/db.tar.gz"))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Upgrade strategy", func() {
+		It("Should roll back a canary which doesn't become healthy", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-canary",
+				NodeCount:      2,
+				UpgradeStrategy: chainv1alpha1.UpgradeStrategy{
+					Type:                    chainv1alpha1.CanaryStrategy,
+					Canary:                  &chainv1alpha1.CanarySpec{Nodes: 1},
+					ProgressDeadlineSeconds: 5,
+					FailurePolicy:           chainv1alpha1.RollbackOnFailure,
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-canary",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			image := func(name string) func() string {
+				return func() string {
+					sts := &appsv1.StatefulSet{}
+					_ = k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}, sts)
+					if len(sts.Spec.Template.Spec.Containers) == 0 {
+						return ""
+					}
+					return sts.Spec.Template.Spec.Containers[0].Image
+				}
+			}
+			Eventually(image("avago-test-canary-1"), timeout, interval).Should(HaveSuffix(":v1.6.3"))
+
+			By("Changing the image tag")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				f.Spec.Tag = "v1.6.4"
+				return k8sClient.Update(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			By("Checking that only the canary node is upgraded")
+			Eventually(image("avago-test-canary-0"), timeout, interval).Should(HaveSuffix(":v1.6.4"))
+			Expect(image("avago-test-canary-1")()).Should(HaveSuffix(":v1.6.3"))
+
+			By("Moving the clock past the progress deadline of the canary")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() []chainv1alpha1.UpgradedNode {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				if fetched.Status.Upgrade == nil {
+					return nil
+				}
+				return fetched.Status.Upgrade.Nodes
+			}, timeout, interval).Should(HaveLen(1))
+			testClock.Step(10 * time.Second)
+
+			By("Checking that the canary is rolled back, since testEnv does not run pods")
+			Eventually(func() chainv1alpha1.UpgradePhase {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				if fetched.Status.Upgrade == nil {
+					return ""
+				}
+				return fetched.Status.Upgrade.Phase
+			}, timeout, interval).Should(Equal(chainv1alpha1.UpgradeRolledBack))
+			Expect(fetched.Status.Upgrade.FromImage).Should(HaveSuffix(":v1.6.3"))
+			Expect(fetched.Status.Upgrade.Nodes).Should(HaveLen(1))
+			Expect(fetched.Status.Upgrade.Message).Should(ContainSubstring("node 0"))
+			Eventually(image("avago-test-canary-0"), timeout, interval).Should(HaveSuffix(":v1.6.3"))
+			Consistently(image("avago-test-canary-1"), time.Second, interval).Should(HaveSuffix(":v1.6.3"))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+
+		It("Should keep updating the nodes while an upgrade is paused, until it is resumed", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-paused",
+				NodeCount:      2,
+				UpgradeStrategy: chainv1alpha1.UpgradeStrategy{
+					ProgressDeadlineSeconds: 5,
+					FailurePolicy:           chainv1alpha1.PauseOnFailure,
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-paused",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			container := func(name string) func() corev1.Container {
+				return func() corev1.Container {
+					sts := &appsv1.StatefulSet{}
+					_ = k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}, sts)
+					for _, c := range sts.Spec.Template.Spec.Containers {
+						if c.Name == "avago" {
+							return c
+						}
+					}
+					return corev1.Container{}
+				}
+			}
+			image := func(name string) func() string {
+				return func() string {
+					return container(name)().Image
+				}
+			}
+			Eventually(image("avago-test-paused-1"), timeout, interval).Should(HaveSuffix(":v1.6.3"))
+
+			By("Changing the image tag")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				f.Spec.Tag = "v1.6.4"
+				return k8sClient.Update(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			By("Moving the clock past the progress deadline of the first node")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() []chainv1alpha1.UpgradedNode {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				if fetched.Status.Upgrade == nil {
+					return nil
+				}
+				return fetched.Status.Upgrade.Nodes
+			}, timeout, interval).Should(HaveLen(1))
+			testClock.Step(10 * time.Second)
+
+			By("Checking that the upgrade is paused, since testEnv does not run pods")
+			Eventually(func() chainv1alpha1.UpgradePhase {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				if fetched.Status.Upgrade == nil {
+					return ""
+				}
+				return fetched.Status.Upgrade.Phase
+			}, timeout, interval).Should(Equal(chainv1alpha1.UpgradePaused))
+			Expect(fetched.Status.Upgrade.Nodes).Should(HaveLen(1))
+			pausedAt := fetched.Status.Upgrade.Nodes[0].UpgradeTime.Time
+			Expect(image("avago-test-paused-0")()).Should(HaveSuffix(":v1.6.4"))
+
+			By("Checking that other changes are still applied, without upgrading more nodes")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				f.Spec.Env = append(f.Spec.Env, corev1.EnvVar{Name: "AVAGO_LOG_LEVEL", Value: "debug"})
+				return k8sClient.Update(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+			Eventually(func() []corev1.EnvVar {
+				return container("avago-test-paused-0")().Env
+			}, timeout, interval).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_LOG_LEVEL", Value: "debug"}))
+			Expect(image("avago-test-paused-0")()).Should(HaveSuffix(":v1.6.4"))
+			Consistently(image("avago-test-paused-1"), time.Second, interval).Should(HaveSuffix(":v1.6.3"))
+
+			By("Resuming the upgrade")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				if f.Annotations == nil {
+					f.Annotations = map[string]string{}
+				}
+				f.Annotations[chainv1alpha1.ResumeUpgradeAnnotation] = ""
+				return k8sClient.Update(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+			Eventually(func() bool {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.Upgrade.Nodes[0].UpgradeTime.Time.After(pausedAt)
+			}, timeout, interval).Should(BeTrue())
+			Expect(fetched.Annotations).ShouldNot(HaveKey(chainv1alpha1.ResumeUpgradeAnnotation))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+		It("Should upgrade the other nodes once the canary soak is over", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-soak",
+				NodeCount:      2,
+				UpgradeStrategy: chainv1alpha1.UpgradeStrategy{
+					Type:   chainv1alpha1.CanaryStrategy,
+					Canary: &chainv1alpha1.CanarySpec{Nodes: 1, SoakSeconds: 600},
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-soak",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Serving a healthy and bootstrapped node API, as testEnv does not run pods")
+			listener, err := net.Listen("tcp", "127.0.0.1:9650")
+			Expect(err).ShouldNot(HaveOccurred())
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path == "/ext/health" {
+					_, _ = w.Write([]byte(`{"healthy":true,"checks":{}}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"isBootstrapped":true}}`))
+			}))
+			server.Listener.Close()
+			server.Listener = listener
+			server.Start()
+			defer server.Close()
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			image := func(name string) func() string {
+				return func() string {
+					sts := &appsv1.StatefulSet{}
+					_ = k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}, sts)
+					if len(sts.Spec.Template.Spec.Containers) == 0 {
+						return ""
+					}
+					return sts.Spec.Template.Spec.Containers[0].Image
+				}
+			}
+			Eventually(image("avago-test-soak-1"), timeout, interval).Should(HaveSuffix(":v1.6.3"))
+
+			By("Changing the image tag")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				f.Spec.Tag = "v1.6.4"
+				return k8sClient.Update(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+			Eventually(image("avago-test-soak-0"), timeout, interval).Should(HaveSuffix(":v1.6.4"))
+
+			By("Running the canary node, as there is no StatefulSet controller")
+			sts := &appsv1.StatefulSet{}
+			Eventually(func() error {
+				if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-soak-0", Namespace: AvalanchegoNamespace}, sts); err != nil {
+					return err
+				}
+				sts.Status.ObservedGeneration = sts.Generation
+				sts.Status.Replicas = 1
+				sts.Status.ReadyReplicas = 1
+				sts.Status.UpdatedReplicas = 1
+				return k8sClient.Status().Update(context.Background(), sts)
+			}, timeout, interval).Should(Succeed())
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "avago-test-soak-0-0",
+					Namespace: AvalanchegoNamespace,
+					Labels:    map[string]string{"chain.djtx.network/deployment": "test-soak"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "avago", Image: "avaplatform/avalanchego:v1.6.4"}},
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), pod)).Should(Succeed())
+			pod.Status.Phase = corev1.PodRunning
+			pod.Status.PodIP = "127.0.0.1"
+			pod.Status.PodIPs = []corev1.PodIP{{IP: "127.0.0.1"}}
+			Expect(k8sClient.Status().Update(context.Background(), pod)).Should(Succeed())
+
+			By("Checking that the other node waits for the canary soak")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() *metav1.Time {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				if fetched.Status.Upgrade == nil || len(fetched.Status.Upgrade.Nodes) == 0 {
+					return nil
+				}
+				return fetched.Status.Upgrade.Nodes[0].HealthyTime
+			}, timeout, interval).ShouldNot(BeNil())
+			Consistently(image("avago-test-soak-1"), upgradePollInterval, interval).Should(HaveSuffix(":v1.6.3"))
+
+			By("Moving the clock past the canary soak")
+			testClock.Step(601 * time.Second)
+			Eventually(image("avago-test-soak-1"), timeout, interval).Should(HaveSuffix(":v1.6.4"))
+			Expect(k8sClient.Get(context.Background(), key, fetched)).Should(Succeed())
+			Expect(fetched.Status.Upgrade.Phase).Should(Equal(chainv1alpha1.UpgradeProgressing))
+			Expect(fetched.Status.Upgrade.Nodes).Should(HaveLen(2))
+
+			By("Deleting the scope")
+			Expect(k8sClient.Delete(context.Background(), pod, client.GracePeriodSeconds(0))).Should(Succeed())
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Probes", func() {
+		It("Should probe the health of the nodes", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-probes",
+				NodeCount:      1,
+				Probes: chainv1alpha1.ProbesSpec{
+					Startup: chainv1alpha1.ProbeSpec{FailureThreshold: 8640},
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-probes",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking the probes and the readiness gate of the node")
+			sts := &appsv1.StatefulSet{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-probes-0", Namespace: AvalanchegoNamespace}, sts)
+			}, timeout, interval).Should(Succeed())
+			container := sts.Spec.Template.Spec.Containers[0]
+			Expect(container.ReadinessProbe).ShouldNot(BeNil())
+			Expect(container.ReadinessProbe.HTTPGet).ShouldNot(BeNil())
+			Expect(container.ReadinessProbe.HTTPGet.Path).Should(Equal("/ext/health"))
+			Expect(container.StartupProbe).ShouldNot(BeNil())
+			Expect(container.StartupProbe.FailureThreshold).Should(Equal(int32(8640)))
+			Expect(container.LivenessProbe).ShouldNot(BeNil())
+			Expect(container.LivenessProbe.TCPSocket).ShouldNot(BeNil())
+			Expect(sts.Spec.Template.Spec.ReadinessGates).Should(ContainElement(corev1.PodReadinessGate{ConditionType: "chain.djtx.network/bootstrapped"}))
+
+			By("Checking that peers find the node before it is ready, but API requests don't")
+			svc := &corev1.Service{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-probes-0-service", Namespace: AvalanchegoNamespace}, svc)).Should(Succeed())
+			Expect(svc.Spec.PublishNotReadyAddresses).Should(BeTrue())
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-probes-api", Namespace: AvalanchegoNamespace}, svc)
+			}, timeout, interval).Should(Succeed())
+			Expect(svc.Spec.PublishNotReadyAddresses).Should(BeFalse())
+			Expect(svc.Spec.Selector).Should(Equal(map[string]string{"chain.djtx.network/deployment": "test-probes"}))
+			Expect(sts.Spec.Template.Labels).Should(HaveKeyWithValue("chain.djtx.network/deployment", "test-probes"))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Bootstrap config", func() {
+		It("Should write the bootstrapper into the config of the other nodes", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-bootstrap",
+				NodeCount:      2,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-bootstrap",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking that the bootstrapper doesn't bootstrap from anyone")
+			sts := &appsv1.StatefulSet{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-bootstrap-0", Namespace: AvalanchegoNamespace}, sts)
+			}, timeout, interval).Should(Succeed())
+			Expect(sts.Spec.Template.Spec.InitContainers).Should(BeEmpty())
+			Expect(sts.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_BOOTSTRAP_IPS", Value: ""}))
+			for _, volume := range sts.Spec.Template.Spec.Volumes {
+				Expect(volume.ConfigMap).Should(BeNil())
+			}
+
+			By("Checking that the other nodes mount the bootstrap config")
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-bootstrap-1", Namespace: AvalanchegoNamespace}, sts)
+			}, timeout, interval).Should(Succeed())
+			Expect(sts.Spec.Template.Spec.InitContainers).Should(BeEmpty())
+			Expect(sts.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_CONFIG_FILE", Value: "/etc/avalanchego/conf/conf.json"}))
+			Expect(sts.Spec.Template.Spec.Volumes).Should(ContainElement(corev1.Volume{
+				Name: "bootstrap-config",
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "avago-test-bootstrap-bootstrap"},
+						Items:                []corev1.KeyToPath{{Key: "conf.json", Path: "conf.json"}},
+						DefaultMode:          &[]int32{0644}[0],
+					},
+				},
+			}))
+
+			By("Checking that there is no config until the bootstrapper runs")
+			cm := &corev1.ConfigMap{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-bootstrap-bootstrap", Namespace: AvalanchegoNamespace}, cm)
+			}, timeout, interval).Should(Succeed())
+			Expect(cm.Data).ShouldNot(HaveKey("conf.json"))
+
+			By("Starting the pod of the bootstrapper, as there is no StatefulSet controller")
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "avago-test-bootstrap-0-0",
+					Namespace: AvalanchegoNamespace,
+					Labels:    map[string]string{"chain.djtx.network/deployment": "test-bootstrap"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "avago", Image: "avaplatform/avalanchego:v1.6.3"}},
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), pod)).Should(Succeed())
+			pod.Status.Phase = corev1.PodRunning
+			pod.Status.PodIP = "10.0.0.10"
+			pod.Status.PodIPs = []corev1.PodIP{{IP: "10.0.0.10"}}
+			Expect(k8sClient.Status().Update(context.Background(), pod)).Should(Succeed())
+
+			By("Checking that the config has the IP and NodeID of the bootstrapper")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				if len(fetched.Status.Nodes) == 0 {
+					return ""
+				}
+				return fetched.Status.Nodes[0].NodeID
+			}, timeout, interval).ShouldNot(BeEmpty())
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-bootstrap-bootstrap", Namespace: AvalanchegoNamespace}, cm)
+				return cm.Data["conf.json"]
+			}, timeout, interval).Should(MatchJSON(`{"bootstrap-ips":"10.0.0.10:9651","bootstrap-ids":"` + fetched.Status.Nodes[0].NodeID + `"}`))
+
+			By("Deleting the scope")
+			Expect(k8sClient.Delete(context.Background(), pod, client.GracePeriodSeconds(0))).Should(Succeed())
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Multiple bootstrappers", func() {
+		It("Should bootstrap from a quorum of beacons", func() {
+			first, second := 0, 1
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-beacons",
+				NodeCount:      3,
+				Bootstrappers: []chainv1alpha1.BootstrapperSpec{
+					{Node: &first},
+					{Node: &second},
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-beacons",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking that every bootstrapper has its own config, and the other nodes share one")
+			configKeys := map[string]string{
+				"avago-test-beacons-0": "conf-0.json",
+				"avago-test-beacons-1": "conf-1.json",
+				"avago-test-beacons-2": "conf.json",
+			}
+			for name, configKey := range configKeys {
+				sts := &appsv1.StatefulSet{}
+				Eventually(func() error {
+					return k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}, sts)
+				}, timeout, interval).Should(Succeed())
+				Expect(sts.Spec.Template.Spec.Containers[0].Env).ShouldNot(ContainElement(corev1.EnvVar{Name: "AVAGO_BOOTSTRAP_IPS", Value: ""}))
+				Expect(sts.Spec.Template.Spec.Volumes).Should(ContainElement(WithTransform(func(v corev1.Volume) []corev1.KeyToPath {
+					if v.ConfigMap == nil {
+						return nil
+					}
+					return v.ConfigMap.Items
+				}, Equal([]corev1.KeyToPath{{Key: configKey, Path: "conf.json"}}))))
+			}
+
+			By("Checking that the bootstrappers start right away, while the other nodes wait for them")
+			cm := &corev1.ConfigMap{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-beacons-bootstrap", Namespace: AvalanchegoNamespace}, cm)
+			}, timeout, interval).Should(Succeed())
+			Expect(cm.Data).Should(HaveKeyWithValue("conf-0.json", `{"bootstrap-ips":"","bootstrap-ids":""}`))
+			Expect(cm.Data).Should(HaveKeyWithValue("conf-1.json", `{"bootstrap-ips":"","bootstrap-ids":""}`))
+			Expect(cm.Data).ShouldNot(HaveKey("conf.json"))
+
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.BootstrapperURL
+			}, timeout, interval).Should(Equal("avago-test-beacons-0-service,avago-test-beacons-1-service"))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Large networks", func() {
+		It("Should give every node the role of its index", func() {
+			// Pre-defined secrets spare generating 50 staking keys, the secrets themselves are not read by the StatefulSets
+			// in the test environment. Such nodes are created one at a time, once the nodes before them are ready.
+			const (
+				nodeCount    = 50
+				largeTimeout = time.Minute * 5
+			)
+			existingSecrets := make([]string, 0, nodeCount)
+			for i := 0; i < nodeCount; i++ {
+				existingSecrets = append(existingSecrets, fmt.Sprintf("test-large-secret-%d", i))
+			}
+
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:             "v1.6.3",
+				DeploymentName:  "test-large",
+				NodeCount:       nodeCount,
+				ExistingSecrets: existingSecrets,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-large",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+			// markReady stands in for the StatefulSet controller, which doesn't run in the test environment
+			markReady := func(sts *appsv1.StatefulSet) error {
+				sts.Status.ObservedGeneration = sts.Generation
+				sts.Status.Replicas = 1
+				sts.Status.ReadyReplicas = 1
+				sts.Status.UpdatedReplicas = 1
+				return k8sClient.Status().Update(context.Background(), sts)
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking every node as it is created")
+			for i := 0; i < nodeCount; i++ {
+				name := fmt.Sprintf("avago-test-large-%d", i)
+				sts := &appsv1.StatefulSet{}
+				Eventually(func() error {
+					if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}, sts); err != nil {
+						return err
+					}
+					return markReady(sts)
+				}, largeTimeout, interval).Should(Succeed())
+				// Only node 0 starts without bootstrappers
+				if i == 0 {
+					Expect(sts.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_BOOTSTRAP_IPS", Value: ""}))
+				} else {
+					Expect(sts.Spec.Template.Spec.Containers[0].Env).ShouldNot(ContainElement(corev1.EnvVar{Name: "AVAGO_BOOTSTRAP_IPS", Value: ""}))
+					Expect(sts.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_CONFIG_FILE", Value: "/etc/avalanchego/conf/conf.json"}))
+				}
+			}
+
+			By("Checking the roles of the nodes")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() int {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetched.Status.ReadyNodes
+			}, largeTimeout, interval).Should(Equal(nodeCount))
+			Expect(fetched.Status.Nodes).Should(HaveLen(nodeCount))
+			for i, node := range fetched.Status.Nodes {
+				Expect(node.Index).Should(Equal(i))
+				if i == 0 {
+					Expect(node.Role).Should(Equal(chainv1alpha1.NodeRoleBootstrapper))
+				} else {
+					Expect(node.Role).Should(Equal(chainv1alpha1.NodeRoleNode))
+				}
+				Expect(node.ServiceName).Should(Equal(fmt.Sprintf("avago-test-large-%d-service.%s.svc", i, AvalanchegoNamespace)))
+			}
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, largeTimeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Network ID", func() {
+		It("Should start a custom network with the given ID", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-network-id",
+				NodeCount:      1,
+				NetworkID:      1000,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-network-id",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking that the nodes run the network ID and its genesis")
+			sts := &appsv1.StatefulSet{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-network-id-0", Namespace: AvalanchegoNamespace}, sts)
+			}, timeout, interval).Should(Succeed())
+			Expect(sts.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_NETWORK_ID", Value: "1000"}))
+			Expect(sts.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_GENESIS", Value: "/etc/avalanchego/st-certs/genesis.json"}))
+
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetchGenesis(fetched)
+			}, timeout, interval).Should(ContainSubstring(`"networkID":1000,`))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+
+		It("Should join fuji through its public beacons", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-fuji",
+				NodeCount:      2,
+				Network:        chainv1alpha1.FujiNetwork,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-fuji",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking that the nodes run fuji without a genesis or bootstrappers")
+			for _, name := range []string{"avago-test-fuji-0", "avago-test-fuji-1"} {
+				sts := &appsv1.StatefulSet{}
+				Eventually(func() error {
+					return k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: AvalanchegoNamespace}, sts)
+				}, timeout, interval).Should(Succeed())
+				Expect(sts.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_NETWORK_ID", Value: "5"}))
+				for _, envName := range []string{"AVAGO_GENESIS", "AVAGO_BOOTSTRAP_IPS", "AVAGO_CONFIG_FILE"} {
+					Expect(sts.Spec.Template.Spec.Containers[0].Env).ShouldNot(ContainElement(HaveField("Name", envName)))
+				}
+			}
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Genesis template", func() {
+		It("Should generate the genesis from the template", func() {
+			fee := 20000
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-genesis-template",
+				NodeCount:      1,
+				GenesisTemplate: &chainv1alpha1.GenesisTemplate{
+					InitialStakeDuration: 86400,
+					Stakers:              []chainv1alpha1.GenesisStaker{{DelegationFee: &fee}},
+					Message:              "Generated from a template",
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-genesis-template",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking the generated genesis")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetchGenesis(fetched)
+			}, timeout, interval).ShouldNot(BeEmpty())
+			var genesis common.Genesis
+			Expect(json.Unmarshal([]byte(fetchGenesis(fetched)), &genesis)).Should(Succeed())
+			Expect(genesis.Message).Should(Equal("Generated from a template"))
+			Expect(genesis.InitialStakeDuration).Should(Equal(86400))
+			Expect(genesis.InitialStakers).Should(HaveLen(1))
+			Expect(genesis.InitialStakers[0].DelegationFee).Should(Equal(20000))
+			Expect(genesis.InitialStakers[0].RewardAddress).Should(Equal(common.DefaultRewardAddress))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("C-Chain", func() {
+		It("Should render the C-Chain genesis and config", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-c-chain",
+				NodeCount:      1,
+				CChain: &chainv1alpha1.CChainSpec{
+					ChainID: 99999,
+					Config:  &chainv1alpha1.CChainConfig{LogLevel: "debug"},
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-c-chain",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking the C-Chain genesis")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetchGenesis(fetched)
+			}, timeout, interval).ShouldNot(BeEmpty())
+			var genesis common.Genesis
+			Expect(json.Unmarshal([]byte(fetchGenesis(fetched)), &genesis)).Should(Succeed())
+			var cChain common.CChainGenesis
+			Expect(json.Unmarshal([]byte(genesis.CChainGenesis), &cChain)).Should(Succeed())
+			Expect(cChain.Config.ChainID).Should(Equal(int64(99999)))
+
+			By("Checking the C-Chain config")
+			cm := &corev1.ConfigMap{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{
+					Name:      "avago-test-c-chain-chain-config",
+					Namespace: AvalanchegoNamespace,
+				}, cm)
+			}, timeout, interval).Should(Succeed())
+			Expect(cm.Data).Should(HaveKeyWithValue("C.json", `{"log-level":"debug"}`))
+
+			sts := &appsv1.StatefulSet{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{
+					Name:      "avago-test-c-chain-0",
+					Namespace: AvalanchegoNamespace,
+				}, sts)
+			}, timeout, interval).Should(Succeed())
+			Expect(sts.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_CHAIN_CONFIG_DIR", Value: "/etc/avalanchego/chains"}))
+			Expect(sts.Spec.Template.Annotations).Should(HaveKey("chain.djtx.network/chain-config-hash"))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Genesis start time", func() {
+		It("Should start the genesis before the creation of the network", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-genesis-start",
+				NodeCount:      1,
+				GenesisTemplate: &chainv1alpha1.GenesisTemplate{
+					StartTimeOffset:      &metav1.Duration{Duration: 10 * time.Minute},
+					InitialStakeDuration: 86400,
+				},
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-genesis-start",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking the genesis times in status")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() *metav1.Time {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				if len(fetched.Status.Nodes) == 0 {
+					return nil
+				}
+				return fetched.Status.Nodes[0].StakeEndTime
+			}, timeout, interval).ShouldNot(BeNil())
+			Expect(fetched.Status.GenesisStartTime).ShouldNot(BeNil())
+			start := fetched.Status.GenesisStartTime.Time
+			Expect(start).Should(BeTemporally("~", fetched.CreationTimestamp.Add(-10*time.Minute), time.Second))
+			Expect(fetched.Status.Nodes[0].GenesisStaker).Should(BeTrue())
+			Expect(fetched.Status.Nodes[0].StakeEndTime.Time).Should(BeTemporally("==", start.Add(24*time.Hour)))
+
+			var genesis common.Genesis
+			Expect(json.Unmarshal([]byte(fetchGenesis(fetched)), &genesis)).Should(Succeed())
+			Expect(int64(genesis.StartTime)).Should(Equal(start.Unix()))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Genesis ConfigMap", func() {
+		It("Should mount the genesis from an immutable ConfigMap", func() {
+			spec := chainv1alpha1.AvalanchegoSpec{
+				Tag:            "v1.6.3",
+				DeploymentName: "test-genesis-cm",
+				NodeCount:      1,
+			}
+			key := types.NamespacedName{
+				Name:      "avalanchego-test-genesis-cm",
+				Namespace: AvalanchegoNamespace,
+			}
+			toCreate := &chainv1alpha1.Avalanchego{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       AvalanchegoKind,
+					APIVersion: AvalanchegoAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: spec,
+			}
+
+			By("Creating Avalanchego chain successfully")
+			Expect(k8sClient.Create(context.Background(), toCreate)).Should(Succeed())
+
+			By("Checking the genesis ConfigMap")
+			fetched := &chainv1alpha1.Avalanchego{}
+			Eventually(func() string {
+				_ = k8sClient.Get(context.Background(), key, fetched)
+				return fetchGenesis(fetched)
+			}, timeout, interval).ShouldNot(BeEmpty())
+			Expect(fetched.Status.Genesis).Should(BeEmpty())
+			Expect(fetched.Status.GenesisHash).Should(HaveLen(64))
+			Expect(fetched.Status.GenesisConfigMap).Should(Equal("avago-test-genesis-cm-genesis-" + fetched.Status.GenesisHash[:10]))
+
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: fetched.Status.GenesisConfigMap, Namespace: AvalanchegoNamespace}, cm)).Should(Succeed())
+			Expect(cm.Immutable).ShouldNot(BeNil())
+			Expect(*cm.Immutable).Should(BeTrue())
+
+			network := &corev1.Secret{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-genesis-cm-network", Namespace: AvalanchegoNamespace}, network)).Should(Succeed())
+			Expect(cm.Data["genesis.json"]).Should(Equal(string(network.Data["genesis.json"])))
+
+			By("Checking that the nodes mount it")
+			sts := &appsv1.StatefulSet{}
+			Eventually(func() error {
+				return k8sClient.Get(context.Background(), types.NamespacedName{
+					Name:      "avago-test-genesis-cm-0",
+					Namespace: AvalanchegoNamespace,
+				}, sts)
+			}, timeout, interval).Should(Succeed())
+			Expect(sts.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{Name: "AVAGO_GENESIS", Value: "/etc/avalanchego/genesis/genesis.json"}))
+			var genesisVolume *corev1.Volume
+			for i, v := range sts.Spec.Template.Spec.Volumes {
+				if v.Name == "genesis" {
+					genesisVolume = &sts.Spec.Template.Spec.Volumes[i]
+				}
+			}
+			Expect(genesisVolume).ShouldNot(BeNil())
+			Expect(genesisVolume.ConfigMap.Name).Should(Equal(fetched.Status.GenesisConfigMap))
+
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "avago-test-genesis-cm-0-key", Namespace: AvalanchegoNamespace}, secret)).Should(Succeed())
+			Expect(secret.Data).ShouldNot(HaveKey("genesis.json"))
+
+			By("Deleting the scope")
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				_ = k8sClient.Get(context.Background(), key, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() error {
+				f := &chainv1alpha1.Avalanchego{}
+				return k8sClient.Get(context.Background(), key, f)
+			}, timeout, interval).ShouldNot(Succeed())
+		})
+	})
 })
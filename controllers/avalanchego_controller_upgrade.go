@@ -0,0 +1,387 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
+)
+
+const (
+	eventUpgradeStarted    = "UpgradeStarted"
+	eventUpgradeCompleted  = "UpgradeCompleted"
+	eventUpgradePaused     = "UpgradePaused"
+	eventUpgradeResumed    = "UpgradeResumed"
+	eventUpgradeRolledBack = "UpgradeRolledBack"
+
+	// upgradePollInterval is how often upgraded nodes are checked, until they are healthy
+	upgradePollInterval = 10 * time.Second
+)
+
+// upgradePlan tells which nodes may be updated while an upgrade is in progress
+type upgradePlan struct {
+	all   bool
+	nodes map[int]bool
+}
+
+func (p *upgradePlan) canUpdate(nodeId int) bool {
+	return p.all || p.nodes[nodeId]
+}
+
+// specImage returns the image the nodes are meant to run, e.g. avaplatform/avalanchego:v1.6.3
+func specImage(instance *chainv1alpha1.Avalanchego) string {
+	return instance.Spec.Image + ":" + instance.Spec.Tag
+}
+
+// nodeImage returns the image and tag a node runs: the ones of the spec, unless their upgrade was rolled back,
+// or was paused before the node was upgraded
+func nodeImage(instance *chainv1alpha1.Avalanchego, nodeId int) (string, string) {
+	upgrade := instance.Status.Upgrade
+	if upgrade != nil && upgrade.ToImage == specImage(instance) && (upgrade.Phase == chainv1alpha1.UpgradeRolledBack || isHeldBack(instance, nodeId)) {
+		if i := strings.LastIndex(upgrade.FromImage, ":"); i != -1 {
+			return upgrade.FromImage[:i], upgrade.FromImage[i+1:]
+		}
+	}
+	return instance.Spec.Image, instance.Spec.Tag
+}
+
+// isHeldBack returns true if the upgrade is paused and the node isn't upgraded
+func isHeldBack(instance *chainv1alpha1.Avalanchego, nodeId int) bool {
+	upgrade := instance.Status.Upgrade
+	if upgrade == nil || upgrade.Phase != chainv1alpha1.UpgradePaused {
+		return false
+	}
+	for _, node := range upgrade.Nodes {
+		if node.Index == nodeId {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcileUpgrade follows the upgrade of the nodes to the image of the spec, see spec.upgradeStrategy.
+// Nodes are only upgraded once the nodes upgraded before them are ready, healthy and bootstrapped.
+// Returns the nodes which may be updated, or nil if no upgrade is in progress and the nodes are updated as usual.
+// The duration tells when to check on the upgraded nodes again.
+func (r *AvalanchegoReconciler) reconcileUpgrade(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) (*upgradePlan, time.Duration, error) {
+	resume := false
+	if _, ok := instance.Annotations[chainv1alpha1.ResumeUpgradeAnnotation]; ok {
+		if err := r.removeResumeAnnotation(ctx, instance); err != nil {
+			return nil, 0, err
+		}
+		resume = true
+	}
+
+	target := specImage(instance)
+	images, err := r.nodeImages(ctx, instance)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	changed := false
+	upgrade := instance.Status.Upgrade
+	if upgrade == nil || upgrade.ToImage != target {
+		// The node with the highest index is the last one to be upgraded, it runs the oldest image
+		from := ""
+		for i := instance.Spec.NodeCount - 1; i >= 0 && from == ""; i-- {
+			if image, ok := images[i]; ok && image != target {
+				from = image
+			}
+		}
+		if from == "" {
+			// A new network, or the nodes already run the image
+			return nil, 0, nil
+		}
+		l.Info("Starting upgrade", "from", from, "to", target)
+		upgrade = &chainv1alpha1.UpgradeStatus{
+			FromImage: from,
+			ToImage:   target,
+			Phase:     chainv1alpha1.UpgradeProgressing,
+			StartTime: metav1.NewTime(r.Clock.Now()),
+		}
+		instance.Status.Upgrade = upgrade
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventUpgradeStarted, "Upgrading nodes from %s to %s", from, target)
+		changed = true
+	}
+
+	switch upgrade.Phase {
+	case chainv1alpha1.UpgradeCompleted:
+		return nil, 0, r.updateUpgradeStatus(ctx, instance, changed, l)
+	case chainv1alpha1.UpgradeRolledBack:
+		// Every node goes back to the previous image right away, see nodeImage
+		return &upgradePlan{all: true}, 0, r.updateUpgradeStatus(ctx, instance, changed, l)
+	case chainv1alpha1.UpgradePaused:
+		if !resume {
+			// The nodes are updated as usual, the ones which are not upgraded keep the previous image, see nodeImage
+			return nil, 0, r.updateUpgradeStatus(ctx, instance, changed, l)
+		}
+		r.resumeUpgrade(instance, l)
+		changed = true
+	}
+
+	strategy := instance.Spec.WithDefaults().UpgradeStrategy
+	maxUnavailable := strategy.MaxUnavailable
+	deadline := time.Duration(strategy.ProgressDeadlineSeconds) * time.Second
+	canaryNodes, soak := 0, time.Duration(0)
+	if strategy.Type == chainv1alpha1.CanaryStrategy {
+		canaryNodes = strategy.Canary.Nodes
+		soak = time.Duration(strategy.Canary.SoakSeconds) * time.Second
+	}
+
+	now := r.Clock.Now()
+	inProgress := 0
+	var lastHealthy time.Time
+	for i := range upgrade.Nodes {
+		node := &upgrade.Nodes[i]
+		isCanary := i < canaryNodes
+		// Canary nodes have to stay healthy until the soak time is over
+		if node.HealthyTime != nil && !(isCanary && soak > 0 && len(upgrade.Nodes) <= canaryNodes) {
+			continue
+		}
+		healthy, message, err := r.isNodeUpgraded(ctx, instance, node.Index, target)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch {
+		case healthy && node.HealthyTime == nil:
+			l.Info("Upgraded node is healthy", "node", node.Index)
+			node.HealthyTime = &metav1.Time{Time: now}
+			changed = true
+		case !healthy && node.HealthyTime != nil:
+			r.failUpgrade(instance, fmt.Sprintf("canary node %d became unhealthy: %s", node.Index, message), l)
+			return r.upgradePlanAfterFailure(ctx, instance, l)
+		case !healthy && now.Sub(node.UpgradeTime.Time) > deadline:
+			r.failUpgrade(instance, fmt.Sprintf("node %d didn't become healthy within %s: %s", node.Index, deadline, message), l)
+			return r.upgradePlanAfterFailure(ctx, instance, l)
+		case !healthy:
+			inProgress++
+		}
+		if node.HealthyTime != nil && node.HealthyTime.Time.After(lastHealthy) {
+			lastHealthy = node.HealthyTime.Time
+		}
+	}
+
+	budget := maxUnavailable - inProgress
+	switch {
+	case len(upgrade.Nodes) < canaryNodes:
+		// The canary nodes are upgraded together
+		budget = canaryNodes - len(upgrade.Nodes)
+	case len(upgrade.Nodes) == canaryNodes && canaryNodes > 0:
+		if inProgress > 0 || now.Before(lastHealthy.Add(soak)) {
+			budget = 0
+		}
+	}
+
+	upgrading := map[int]bool{}
+	for _, node := range upgrade.Nodes {
+		upgrading[node.Index] = true
+	}
+	remaining := 0
+	for i := 0; i < instance.Spec.NodeCount; i++ {
+		image, exists := images[i]
+		if !exists || image == target || upgrading[i] {
+			continue
+		}
+		if budget <= 0 {
+			remaining++
+			continue
+		}
+		l.Info("Upgrading node", "node", i, "image", target)
+		upgrade.Nodes = append(upgrade.Nodes, chainv1alpha1.UpgradedNode{
+			Index:       i,
+			UpgradeTime: metav1.Time{Time: now},
+		})
+		upgrading[i] = true
+		budget--
+		inProgress++
+		changed = true
+	}
+
+	if remaining == 0 && inProgress == 0 {
+		l.Info("Upgrade completed", "image", target)
+		upgrade.Phase = chainv1alpha1.UpgradeCompleted
+		upgrade.CompletionTime = &metav1.Time{Time: now}
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventUpgradeCompleted, "All the nodes run %s", target)
+		return nil, 0, r.updateUpgradeStatus(ctx, instance, true, l)
+	}
+	return &upgradePlan{nodes: upgrading}, upgradePollInterval, r.updateUpgradeStatus(ctx, instance, changed, l)
+}
+
+// failUpgrade pauses or rolls back the upgrade, depending on spec.upgradeStrategy.failurePolicy
+func (r *AvalanchegoReconciler) failUpgrade(instance *chainv1alpha1.Avalanchego, message string, l logr.Logger) {
+	upgrade := instance.Status.Upgrade
+	upgrade.Message = message
+	if instance.Spec.UpgradeStrategy.FailurePolicy == chainv1alpha1.RollbackOnFailure {
+		l.Info("Rolling back upgrade", "image", upgrade.FromImage, "reason", message)
+		upgrade.Phase = chainv1alpha1.UpgradeRolledBack
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventUpgradeRolledBack, "Rolling back to %s: %s", upgrade.FromImage, message)
+		return
+	}
+	l.Info("Pausing upgrade", "reason", message)
+	upgrade.Phase = chainv1alpha1.UpgradePaused
+	r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventUpgradePaused, "Upgrade to %s paused: %s", upgrade.ToImage, message)
+}
+
+// resumeUpgrade moves a paused upgrade back to Progressing.
+// The upgraded nodes are checked again, with a new progress deadline and canary soak.
+func (r *AvalanchegoReconciler) resumeUpgrade(instance *chainv1alpha1.Avalanchego, l logr.Logger) {
+	upgrade := instance.Status.Upgrade
+	l.Info("Resuming upgrade", "image", upgrade.ToImage)
+	upgrade.Phase = chainv1alpha1.UpgradeProgressing
+	upgrade.Message = ""
+	now := metav1.NewTime(r.Clock.Now())
+	for i := range upgrade.Nodes {
+		upgrade.Nodes[i].UpgradeTime = now
+		upgrade.Nodes[i].HealthyTime = nil
+	}
+	r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventUpgradeResumed, "Resuming upgrade to %s", upgrade.ToImage)
+}
+
+// removeResumeAnnotation removes the annotation resuming a paused upgrade, so it only resumes it once.
+// Only the annotation is patched, the status of the instance may have changed since it was read.
+func (r *AvalanchegoReconciler) removeResumeAnnotation(ctx context.Context, instance *chainv1alpha1.Avalanchego) error {
+	patched := instance.DeepCopy()
+	delete(patched.Annotations, chainv1alpha1.ResumeUpgradeAnnotation)
+	if err := r.Patch(ctx, patched, client.MergeFrom(instance)); err != nil {
+		return err
+	}
+	instance.Annotations = patched.Annotations
+	instance.ResourceVersion = patched.ResourceVersion
+	return nil
+}
+
+func (r *AvalanchegoReconciler) upgradePlanAfterFailure(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	l logr.Logger,
+) (*upgradePlan, time.Duration, error) {
+	plan := &upgradePlan{all: instance.Status.Upgrade.Phase == chainv1alpha1.UpgradeRolledBack}
+	return plan, 0, r.updateUpgradeStatus(ctx, instance, true, l)
+}
+
+func (r *AvalanchegoReconciler) updateUpgradeStatus(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	changed bool,
+	l logr.Logger,
+) error {
+	if !changed {
+		return nil
+	}
+	if err := r.Status().Update(ctx, instance); err != nil {
+		l.Error(err, "error calling Upgrade status update")
+		return err
+	}
+	return nil
+}
+
+// nodeImages returns the image of the avalanchego container of every existing StatefulSet, by node index
+func (r *AvalanchegoReconciler) nodeImages(ctx context.Context, instance *chainv1alpha1.Avalanchego) (map[int]string, error) {
+	images := map[int]string{}
+	for i := 0; i < instance.Spec.NodeCount; i++ {
+		sts := &appsv1.StatefulSet{}
+		err := r.Get(ctx, types.NamespacedName{Name: avaGoPrefix + nodeBaseName(instance, i), Namespace: instance.Namespace}, sts)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, c := range sts.Spec.Template.Spec.Containers {
+			if c.Name == "avago" {
+				images[i] = c.Image
+			}
+		}
+	}
+	return images, nil
+}
+
+// isNodeUpgraded returns true if the node runs the image, is ready, reports healthy and has bootstrapped the primary network.
+// Otherwise the message tells what the node is waiting for.
+func (r *AvalanchegoReconciler) isNodeUpgraded(
+	ctx context.Context,
+	instance *chainv1alpha1.Avalanchego,
+	nodeId int,
+	image string,
+) (bool, string, error) {
+	name := avaGoPrefix + nodeBaseName(instance, nodeId)
+	sts := &appsv1.StatefulSet{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: instance.Namespace}, sts)
+	if errors.IsNotFound(err) {
+		return false, "the StatefulSet is missing", nil
+	} else if err != nil {
+		return false, "", err
+	}
+	updated := false
+	for _, c := range sts.Spec.Template.Spec.Containers {
+		if c.Name == "avago" {
+			updated = c.Image == image
+		}
+	}
+	if !updated {
+		return false, "the StatefulSet is not updated yet", nil
+	}
+	if !isStatefulSetReady(sts) {
+		return false, "the pod is not ready", nil
+	}
+	if r.NodeAPI == nil {
+		return true, "", nil
+	}
+
+	pod := &corev1.Pod{}
+	err = r.Get(ctx, types.NamespacedName{Name: name + "-0", Namespace: instance.Namespace}, pod)
+	if errors.IsNotFound(err) {
+		return false, "the pod is missing", nil
+	} else if err != nil {
+		return false, "", err
+	}
+	if pod.Status.PodIP == "" {
+		return false, "the pod has no IP", nil
+	}
+	uri := "http://" + pod.Status.PodIP + ":9650"
+	health, err := r.NodeAPI.Health(ctx, uri)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	if !health.Healthy {
+		return false, "unhealthy: " + health.Message, nil
+	}
+	bootstrapped, err := r.NodeAPI.Bootstrapped(ctx, uri, common.PrimaryNetworkChains...)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	if !bootstrapped {
+		return false, "still bootstrapping", nil
+	}
+	return true, "", nil
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// BootstrapConfig is the part of the avalanchego config file telling a node which peers to bootstrap from.
+// The n-th NodeID belongs to the n-th IP.
+type BootstrapConfig struct {
+	BootstrapIPs string `json:"bootstrap-ips"`
+	BootstrapIDs string `json:"bootstrap-ids"`
+}
+
+// Bootstrapper is a node other nodes bootstrap from
+type Bootstrapper struct {
+	IP string
+	// Staking port
+	Port   int
+	NodeID string
+}
+
+// NewBootstrapConfig returns the config of a node bootstrapping from the given bootstrappers
+func NewBootstrapConfig(bootstrappers []Bootstrapper) BootstrapConfig {
+	ips := make([]string, 0, len(bootstrappers))
+	ids := make([]string, 0, len(bootstrappers))
+	for _, b := range bootstrappers {
+		ips = append(ips, net.JoinHostPort(b.IP, strconv.Itoa(b.Port)))
+		ids = append(ids, b.NodeID)
+	}
+	return BootstrapConfig{
+		BootstrapIPs: strings.Join(ips, ","),
+		BootstrapIDs: strings.Join(ids, ","),
+	}
+}
+
+// BootstrapResolver finds the IPs and NodeIDs of the nodes behind a bootstrapper hostname
+type BootstrapResolver struct {
+	// Used to read the NodeID of every bootstrapper
+	NodeAPI NodeAPI
+	// Resolves a hostname to IPs, net.DefaultResolver.LookupHost if nil
+	LookupHost func(ctx context.Context, host string) ([]string, error)
+	// Ports of the HTTP API and of the staking server of the bootstrappers
+	HTTPPort    int
+	StakingPort int
+	// Used by ResolveAll: delay before the first retry, doubled after every attempt up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Log        logr.Logger
+}
+
+// ResolveAll resolves every host, retrying with backoff until all of them answered or ctx is done.
+// It gives up only if no host at all answered, otherwise the bootstrappers found so far are returned.
+func (r *BootstrapResolver) ResolveAll(ctx context.Context, hosts []string) ([]Bootstrapper, error) {
+	found := map[string][]Bootstrapper{}
+	backoff := r.MinBackoff
+	for {
+		var lastErr error
+		for _, host := range hosts {
+			if _, ok := found[host]; ok {
+				continue
+			}
+			bootstrappers, err := r.Resolve(ctx, host)
+			if err != nil {
+				lastErr = err
+				r.Log.Info("Bootstrapper not available yet", "host", host, "error", err.Error())
+				continue
+			}
+			r.Log.Info("Bootstrapper resolved", "host", host, "nodes", len(bootstrappers))
+			found[host] = bootstrappers
+		}
+		if len(found) == len(hosts) {
+			return joinBootstrappers(hosts, found), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if len(found) == 0 {
+				return nil, fmt.Errorf("no bootstrapper answered before the deadline, last error: %w", lastErr)
+			}
+			r.Log.Info("Giving up on the bootstrappers which didn't answer", "found", len(found), "expected", len(hosts))
+			return joinBootstrappers(hosts, found), nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > r.MaxBackoff {
+			backoff = r.MaxBackoff
+		}
+	}
+}
+
+// joinBootstrappers returns the bootstrappers found behind the hosts, in the order of the hosts
+func joinBootstrappers(hosts []string, found map[string][]Bootstrapper) []Bootstrapper {
+	var bootstrappers []Bootstrapper
+	for _, host := range hosts {
+		bootstrappers = append(bootstrappers, found[host]...)
+	}
+	return bootstrappers
+}
+
+// Resolve returns the IP and NodeID of every node behind host, or an error if any of them didn't answer
+func (r *BootstrapResolver) Resolve(ctx context.Context, host string) ([]Bootstrapper, error) {
+	ips, err := r.LookupIPs(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	bootstrappers := make([]Bootstrapper, 0, len(ips))
+	for _, ip := range ips {
+		uri := "http://" + net.JoinHostPort(ip, strconv.Itoa(r.HTTPPort))
+		nodeID, err := r.NodeAPI.NodeID(ctx, uri)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the NodeID of %s: %w", ip, err)
+		}
+		bootstrappers = append(bootstrappers, Bootstrapper{IP: ip, Port: r.StakingPort, NodeID: nodeID})
+	}
+	return bootstrappers, nil
+}
+
+// LookupIPs returns the sorted IPs of host, or host itself if it is an IP
+func (r *BootstrapResolver) LookupIPs(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	lookup := r.LookupHost
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupHost
+	}
+	ips, err := lookup(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't resolve %s: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("%s resolved to no address", host)
+	}
+	sort.Strings(ips)
+	return ips, nil
+}
+
+// WriteBootstrapConfig writes config as a JSON avalanchego config file at path.
+// The file is written next to path first, then renamed, so the node never reads a partial file.
+func WriteBootstrapConfig(path string, config BootstrapConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".conf-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
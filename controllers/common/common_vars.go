@@ -17,58 +17,61 @@ limitations under the License.
 package common
 
 const (
-	AvagoBootstraperFinderScript = `#!/bin/bash
-
-echo "Config path: $CONFIG_PATH/conf.json"
-echo "DNSs to resolve: $BOOTSTRAPPERS"
-
-IFS=',' read -r -a bootstrappers_array <<< "$BOOTSTRAPPERS"
-
-delim=""
-joined_ip=""
-
-for bootstrapper in "${bootstrappers_array[@]}"
-do
-		retry=3
-		dig_out=''
-		echo "--------------------------"
-
-		while [ -z "$dig_out" ] && [ "$retry" -ne "0" ]
-		do
-			if [ "$retry" -ne "3" ]; then
-				sleep 10
-			fi
-			echo "Resolving $bootstrapper"
-			dig_out=$(dig +search +short "$bootstrapper")
-			retry=$((retry-1))
-		done
-
-		echo "List of IPs to add:"
-		IFS=$'\n' read -r -d '' -a ips <<< "$dig_out"
-		for ip in "${ips[@]}"
-		do
-			echo "$ip"
-			joined_ip="$joined_ip$delim$ip"
-			delim=","
-		done
-		echo "--------------------------"
-
-done
-
-if [ -z "$joined_ip" ]; then
-	echo "ERROR no DNS adresses have been resolved"
-	exit 1
+	// AvagoRestoreArchiveScript seeds an empty database with a tar archive, verified with its SHA-256 checksum.
+	// The outcome is written to the termination message, prefixed with a restore phase, e.g. "Succeeded: ...".
+	AvagoRestoreArchiveScript = `#!/bin/sh
+
+ARCHIVE="$DB_DIR/.archive.tmp"
+MARKER="$DB_DIR/.restored-sha256"
+IN_PROGRESS="$DB_DIR/.restoring"
+
+finish() {
+	echo "$2"
+	echo "$2" > /dev/termination-log
+	exit "$1"
+}
+
+if [ "$(cat "$MARKER" 2>/dev/null)" = "$ARCHIVE_SHA256" ]; then
+	finish 0 "Succeeded: restored from the archive before"
 fi
+if [ -f "$IN_PROGRESS" ]; then
+	echo "Removing the leftovers of an interrupted restore"
+	find "$DB_DIR" -mindepth 1 -maxdepth 1 ! -name lost+found ! -name .restoring -exec rm -rf {} +
+elif [ -n "$(find "$DB_DIR" -mindepth 1 -maxdepth 1 ! -name lost+found)" ]; then
+	finish 0 "Skipped: the database is not empty"
+fi
+touch "$IN_PROGRESS" || finish 1 "Failed: $DB_DIR is not writable"
+
+echo "Downloading $ARCHIVE_URL"
+curl -fsSL --retry 5 -o "$ARCHIVE" "$ARCHIVE_URL" || finish 1 "Failed: couldn't download the archive"
+
+actual=$(sha256sum "$ARCHIVE" | cut -d ' ' -f 1)
+if [ "$actual" != "$ARCHIVE_SHA256" ]; then
+	rm -f "$ARCHIVE"
+	finish 1 "Failed: the checksum of the archive is $actual, expected $ARCHIVE_SHA256"
+fi
+
+echo "Unpacking the archive"
+case "${ARCHIVE_URL%%\?*}" in
+	*.tar.gz|*.tgz) tar -xzf "$ARCHIVE" -C "$DB_DIR" ;;
+	*) tar -xf "$ARCHIVE" -C "$DB_DIR" ;;
+esac || finish 1 "Failed: couldn't unpack the archive"
 
-echo "Final json: {\"bootstrap-ips\":\"${joined_ip}:9651\"}"
-touch "$CONFIG_PATH/conf.json"
-echo "{\"bootstrap-ips\":\"${joined_ip}:9651\"}" > "$CONFIG_PATH/conf.json"
-ls $CONFIG_PATH
-echo "Guts of $CONFIG_PATH/conf.json"
-cat $CONFIG_PATH/conf.json
+rm -f "$ARCHIVE"
+echo "$ARCHIVE_SHA256" > "$MARKER"
+rm -f "$IN_PROGRESS"
+finish 0 "Succeeded: restored from the archive"
 `
 )
 
+const (
+	// DefaultRewardAddress receives the staking rewards of the initial stakers of a generated genesis, unless given
+	DefaultRewardAddress = "X-custom18jma8ppw3nhx5r4ap8clazz0dps7rv5u9xde7p"
+
+	// DefaultDelegationFee is the delegation fee of the initial stakers of a generated genesis, unless given
+	DefaultDelegationFee = 5000
+)
+
 // PrivateKey-vmRQiZeXEXYMyJhEiqdC2z5JhuDbxL8ix9UVvjgMu2Er1NepE => P-custom1g65uqn6t77p656w64023nh8nd9updzmxwd59gh
 // PrivateKey-ewoqjP7PxY4yr3iLTpLisriqt94hdyDFNgchSxGGztUrTXtNN => X-custom18jma8ppw3nhx5r4ap8clazz0dps7rv5u9xde7p
 // 56289e99c94b6912bfc12adc093c9b51124f0dc54ac7a766b2bc5ccf558d8027 => 0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC
@@ -47,3 +47,76 @@ type InitialStaker struct {
 	RewardAddress string `json:"rewardAddress"`
 	DelegationFee int    `json:"delegationFee"`
 }
+
+// CChainGenesis is the genesis of the C-Chain, marshalled into Genesis.CChainGenesis.
+// Quantities are hexadecimal strings, as in geth genesis files.
+type CChainGenesis struct {
+	Config     CChainGenesisConfig      `json:"config"`
+	Nonce      string                   `json:"nonce"`
+	Timestamp  string                   `json:"timestamp"`
+	ExtraData  string                   `json:"extraData"`
+	GasLimit   string                   `json:"gasLimit"`
+	Difficulty string                   `json:"difficulty"`
+	MixHash    string                   `json:"mixHash"`
+	Coinbase   string                   `json:"coinbase"`
+	Alloc      map[string]CChainAccount `json:"alloc"`
+	Number     string                   `json:"number"`
+	GasUsed    string                   `json:"gasUsed"`
+	ParentHash string                   `json:"parentHash"`
+}
+
+type CChainGenesisConfig struct {
+	ChainID                     int64  `json:"chainId"`
+	HomesteadBlock              int    `json:"homesteadBlock"`
+	DAOForkBlock                int    `json:"daoForkBlock"`
+	DAOForkSupport              bool   `json:"daoForkSupport"`
+	EIP150Block                 int    `json:"eip150Block"`
+	EIP150Hash                  string `json:"eip150Hash"`
+	EIP155Block                 int    `json:"eip155Block"`
+	EIP158Block                 int    `json:"eip158Block"`
+	ByzantiumBlock              int    `json:"byzantiumBlock"`
+	ConstantinopleBlock         int    `json:"constantinopleBlock"`
+	PetersburgBlock             int    `json:"petersburgBlock"`
+	IstanbulBlock               int    `json:"istanbulBlock"`
+	MuirGlacierBlock            int    `json:"muirGlacierBlock"`
+	ApricotPhase1BlockTimestamp *int   `json:"apricotPhase1BlockTimestamp,omitempty"`
+	ApricotPhase2BlockTimestamp *int   `json:"apricotPhase2BlockTimestamp,omitempty"`
+
+	// Read by EVMs with dynamic fees and precompiles, e.g. subnet-evm
+	FeeConfig                       *CChainFeeConfig        `json:"feeConfig,omitempty"`
+	ContractDeployerAllowListConfig *CChainPrecompileConfig `json:"contractDeployerAllowListConfig,omitempty"`
+	TxAllowListConfig               *CChainPrecompileConfig `json:"txAllowListConfig,omitempty"`
+	ContractNativeMinterConfig      *CChainPrecompileConfig `json:"contractNativeMinterConfig,omitempty"`
+	FeeManagerConfig                *CChainPrecompileConfig `json:"feeManagerConfig,omitempty"`
+}
+
+type CChainAccount struct {
+	Balance string `json:"balance"`
+}
+
+type CChainFeeConfig struct {
+	GasLimit                 int64 `json:"gasLimit"`
+	TargetBlockRate          int64 `json:"targetBlockRate"`
+	MinBaseFee               int64 `json:"minBaseFee"`
+	TargetGas                int64 `json:"targetGas"`
+	BaseFeeChangeDenominator int64 `json:"baseFeeChangeDenominator"`
+	MinBlockGasCost          int64 `json:"minBlockGasCost"`
+	MaxBlockGasCost          int64 `json:"maxBlockGasCost"`
+	BlockGasCostStep         int64 `json:"blockGasCostStep"`
+}
+
+// CChainPrecompileConfig enables a precompile from the genesis block
+type CChainPrecompileConfig struct {
+	BlockTimestamp   int64    `json:"blockTimestamp"`
+	AdminAddresses   []string `json:"adminAddresses,omitempty"`
+	EnabledAddresses []string `json:"enabledAddresses,omitempty"`
+}
+
+// CChainConfig is the config.json of the C-Chain, read by a node from its chain config dir
+type CChainConfig struct {
+	LogLevel          string `json:"log-level,omitempty"`
+	PruningEnabled    *bool  `json:"pruning-enabled,omitempty"`
+	LocalTxsEnabled   *bool  `json:"local-txs-enabled,omitempty"`
+	SnowmanAPIEnabled *bool  `json:"snowman-api-enabled,omitempty"`
+	RPCGasCap         int64  `json:"rpc-gas-cap,omitempty"`
+}
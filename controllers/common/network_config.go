@@ -43,36 +43,68 @@ type KeyPair struct {
 	Id   string
 }
 
-func NewNetwork(networkSize int) (Network, error) {
-	var (
-		g Genesis
-		n Network
-	)
+// DefaultGenesis returns the genesis new networks are generated from, without initial stakers
+func DefaultGenesis() (Genesis, error) {
+	var g Genesis
 	if err := json.Unmarshal([]byte(defaultGenesisConfigJSON), &g); err != nil {
-		return Network{}, fmt.Errorf("couldn't unmarshal local genesis: %w", err)
-	}
-	for i := 0; i < networkSize; i++ {
-		// TODO handle the below error
-		stakingKeyCertPair, _ := newStakingKeyCertPair()
-		fmt.Print("------------------------------------------")
-		fmt.Print(stakingKeyCertPair.Cert)
-		fmt.Print("------------------------------------------")
-		fmt.Print(stakingKeyCertPair.Key)
-		fmt.Print("------------------------------------------")
-		fmt.Print(stakingKeyCertPair.Id)
+		return Genesis{}, fmt.Errorf("couldn't unmarshal local genesis: %w", err)
+	}
+	return g, nil
+}
+
+// NewNetwork generates the staking keys of the initial stakers of g, and fills in their NodeIDs
+func NewNetwork(g Genesis) (Network, error) {
+	var n Network
+	stakers := g.InitialStakers
+	g.InitialStakers = make([]InitialStaker, 0, len(stakers))
+	for i, staker := range stakers {
+		stakingKeyCertPair, err := newStakingKeyCertPair()
+		if err != nil {
+			return Network{}, fmt.Errorf("couldn't generate the staking key of initial staker %d: %w", i, err)
+		}
 		n.KeyPairs = append(n.KeyPairs, stakingKeyCertPair)
-		g.InitialStakers = append(g.InitialStakers, InitialStaker{NodeID: stakingKeyCertPair.Id, RewardAddress: g.Allocations[1].DjtxAddr, DelegationFee: 5000})
+		staker.NodeID = stakingKeyCertPair.Id
+		g.InitialStakers = append(g.InitialStakers, staker)
 	}
 	genesisBytes, err := json.Marshal(g)
 	if err != nil {
-		panic("Error: cannot marshal genesis.json, common package is invalid")
+		return Network{}, fmt.Errorf("couldn't marshal genesis: %w", err)
 	}
 	n.Genesis = string(genesisBytes)
+	return n, nil
+}
 
-	fmt.Print("------------------------------------------")
-	fmt.Print(n.Genesis)
+// NewKeyPair generates a staking certificate and key for a node, which is not part of the genesis
+func NewKeyPair() (KeyPair, error) {
+	return newStakingKeyCertPair()
+}
 
-	return n, nil
+// GenesisStakers returns the NodeIDs of the initial stakers of a genesis, in order
+func GenesisStakers(genesis string) ([]string, error) {
+	var g Genesis
+	if err := json.Unmarshal([]byte(genesis), &g); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal genesis: %w", err)
+	}
+	stakers := make([]string, 0, len(g.InitialStakers))
+	for _, s := range g.InitialStakers {
+		stakers = append(stakers, s.NodeID)
+	}
+	return stakers, nil
+}
+
+// GenesisStakeEndTimes returns the start time of a genesis, and the time the stake of each initial staker ends, by NodeID.
+// As in avalanchego, the stake of the i-th initial staker ends i offsets before the initial stake duration is over.
+func GenesisStakeEndTimes(genesis string) (time.Time, map[string]time.Time, error) {
+	var g Genesis
+	if err := json.Unmarshal([]byte(genesis), &g); err != nil {
+		return time.Time{}, nil, fmt.Errorf("couldn't unmarshal genesis: %w", err)
+	}
+	start := time.Unix(int64(g.StartTime), 0).UTC()
+	ends := make(map[string]time.Time, len(g.InitialStakers))
+	for i, s := range g.InitialStakers {
+		ends[s.NodeID] = start.Add(time.Duration(g.InitialStakeDuration-i*g.InitialStakeDurationOffset) * time.Second)
+	}
+	return start, ends, nil
 }
 
 func newStakingKeyCertPair() (KeyPair, error) {
@@ -110,11 +142,10 @@ func newStakingKeyCertPair() (KeyPair, error) {
 		return KeyPair{}, fmt.Errorf("couldn't write private key: %w", err)
 	}
 
-	id, err := ids.ToShortID(hashing.PubkeyBytesToAddress(certBytes))
+	fullId, err := nodeIDFromCertBytes(certBytes)
 	if err != nil {
-		return KeyPair{}, fmt.Errorf("problem deriving node ID from certificate: %w", err)
+		return KeyPair{}, err
 	}
-	fullId := id.PrefixedString(constants.NodeIDPrefix)
 
 	return KeyPair{
 		Cert: certBuff.String(),
@@ -122,3 +153,24 @@ func newStakingKeyCertPair() (KeyPair, error) {
 		Id:   fullId,
 	}, nil
 }
+
+// NodeIDFromCert derives the NodeID of a node from its PEM encoded staking certificate
+func NodeIDFromCert(cert string) (string, error) {
+	block, _ := pem.Decode([]byte(cert))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", fmt.Errorf("couldn't decode PEM certificate")
+	}
+	// Checking that this is a valid certificate, the NodeID itself is derived from the raw bytes
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		return "", fmt.Errorf("couldn't parse certificate: %w", err)
+	}
+	return nodeIDFromCertBytes(block.Bytes)
+}
+
+func nodeIDFromCertBytes(certBytes []byte) (string, error) {
+	id, err := ids.ToShortID(hashing.PubkeyBytesToAddress(certBytes))
+	if err != nil {
+		return "", fmt.Errorf("problem deriving node ID from certificate: %w", err)
+	}
+	return id.PrefixedString(constants.NodeIDPrefix), nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NodeAPI queries the HTTP APIs of a running avalanchego node.
+// uri is the base address of the node, e.g. http://10.0.0.1:9650
+type NodeAPI interface {
+	// Health returns the result of the node's /ext/health check
+	Health(ctx context.Context, uri string) (HealthResult, error)
+	// NodeID returns the NodeID the node reports with info.getNodeID
+	NodeID(ctx context.Context, uri string) (string, error)
+	// Bootstrapped returns true if the node reports with info.isBootstrapped that it has bootstrapped the given chains
+	Bootstrapped(ctx context.Context, uri string, chains ...string) (bool, error)
+}
+
+// PrimaryNetworkChains are the aliases of the chains every node runs
+var PrimaryNetworkChains = []string{"P", "X", "C"}
+
+type HealthResult struct {
+	Healthy bool
+	// Failing checks, empty if the node is healthy
+	Message string
+}
+
+type nodeAPIClient struct {
+	client *http.Client
+}
+
+// NewNodeAPIClient returns a NodeAPI, which gives up on a request after timeout
+func NewNodeAPIClient(timeout time.Duration) NodeAPI {
+	return &nodeAPIClient{
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type healthReply struct {
+	Checks map[string]struct {
+		Error string `json:"error"`
+	} `json:"checks"`
+	Healthy bool `json:"healthy"`
+}
+
+func (c *nodeAPIClient) Health(ctx context.Context, uri string) (HealthResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri+"/ext/health", nil)
+	if err != nil {
+		return HealthResult{}, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return HealthResult{}, fmt.Errorf("couldn't reach the health API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Unhealthy nodes reply with 503, but the body has the same format
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return HealthResult{}, fmt.Errorf("unexpected health API status: %s", resp.Status)
+	}
+	var reply healthReply
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return HealthResult{}, fmt.Errorf("couldn't decode health API reply: %w", err)
+	}
+
+	var failing []string
+	for name, check := range reply.Checks {
+		if check.Error != "" {
+			failing = append(failing, name+": "+check.Error)
+		}
+	}
+	sort.Strings(failing)
+	return HealthResult{
+		Healthy: reply.Healthy,
+		Message: strings.Join(failing, "; "),
+	}, nil
+}
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// call makes a JSON-RPC call to the given endpoint of the node, e.g. /ext/info
+func (c *nodeAPIClient) call(ctx context.Context, uri, endpoint, method string, params, result interface{}) error {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't call %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected %s status: %s", method, resp.Status)
+	}
+
+	reply := struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("couldn't decode %s reply: %w", method, err)
+	}
+	if reply.Error != nil {
+		return fmt.Errorf("%s failed: %s", method, reply.Error.Message)
+	}
+	return json.Unmarshal(reply.Result, result)
+}
+
+func (c *nodeAPIClient) NodeID(ctx context.Context, uri string) (string, error) {
+	result := struct {
+		NodeID string `json:"nodeID"`
+	}{}
+	if err := c.call(ctx, uri, "/ext/info", "info.getNodeID", struct{}{}, &result); err != nil {
+		return "", err
+	}
+	return result.NodeID, nil
+}
+
+func (c *nodeAPIClient) Bootstrapped(ctx context.Context, uri string, chains ...string) (bool, error) {
+	for _, chain := range chains {
+		result := struct {
+			IsBootstrapped bool `json:"isBootstrapped"`
+		}{}
+		params := struct {
+			Chain string `json:"chain"`
+		}{chain}
+		if err := c.call(ctx, uri, "/ext/info", "info.isBootstrapped", params, &result); err != nil {
+			return false, err
+		}
+		if !result.IsBootstrapped {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+)
+
+// RenderGenesis returns the genesis generated for a new network with nodeCount initial stakers, from genesisTemplate
+// and the default genesis. Unless startTime is given, the network starts startTimeOffset before createdAt.
+// The NodeIDs of the initial stakers are left empty, their keys are generated afterwards.
+func RenderGenesis(spec *chainv1alpha1.AvalanchegoSpec, createdAt time.Time) (Genesis, error) {
+	g, err := DefaultGenesis()
+	if err != nil {
+		return Genesis{}, err
+	}
+	g.NetworkID = int(spec.GetNetworkID())
+
+	t := spec.GenesisTemplate
+	if t == nil {
+		t = &chainv1alpha1.GenesisTemplate{}
+	}
+	if len(t.Allocations) > 0 {
+		g.Allocations = make([]Allocation, 0, len(t.Allocations))
+		for _, a := range t.Allocations {
+			allocation := Allocation{
+				EthAddr:        a.EthAddr,
+				DjtxAddr:       a.DjtxAddr,
+				InitialAmount:  int(a.InitialAmount),
+				UnlockSchedule: make([]UnlockSched, 0, len(a.UnlockSchedule)),
+			}
+			for _, unlock := range a.UnlockSchedule {
+				allocation.UnlockSchedule = append(allocation.UnlockSchedule, UnlockSched{
+					Amount:   int(unlock.Amount),
+					Locktime: int(unlock.Locktime),
+				})
+			}
+			g.Allocations = append(g.Allocations, allocation)
+		}
+	}
+	g.InitialStakedFunds = t.GetInitialStakedFunds()
+	g.StartTime = int(t.GetStartTime(createdAt))
+	g.InitialStakeDuration = int(t.GetInitialStakeDuration())
+	g.InitialStakeDurationOffset = int(t.GetInitialStakeDurationOffset())
+	if t.Message != "" {
+		g.Message = t.Message
+	}
+	if spec.CChain.ChangesGenesis() {
+		var cChain CChainGenesis
+		if err := json.Unmarshal([]byte(g.CChainGenesis), &cChain); err != nil {
+			return Genesis{}, fmt.Errorf("couldn't unmarshal C-Chain genesis: %w", err)
+		}
+		if err := renderCChainGenesis(spec.CChain, &cChain); err != nil {
+			return Genesis{}, err
+		}
+		b, err := json.Marshal(cChain)
+		if err != nil {
+			return Genesis{}, fmt.Errorf("couldn't marshal C-Chain genesis: %w", err)
+		}
+		g.CChainGenesis = string(b)
+	}
+
+	g.InitialStakers = make([]InitialStaker, 0, spec.NodeCount)
+	for i := 0; i < spec.NodeCount; i++ {
+		staker := InitialStaker{
+			RewardAddress: DefaultRewardAddress,
+			DelegationFee: DefaultDelegationFee,
+		}
+		// The last entry applies to the remaining nodes
+		if len(t.Stakers) > 0 {
+			s := t.Stakers[len(t.Stakers)-1]
+			if i < len(t.Stakers) {
+				s = t.Stakers[i]
+			}
+			if s.RewardAddress != "" {
+				staker.RewardAddress = s.RewardAddress
+			}
+			if s.DelegationFee != nil {
+				staker.DelegationFee = *s.DelegationFee
+			}
+		}
+		g.InitialStakers = append(g.InitialStakers, staker)
+	}
+	return g, nil
+}
+
+// RenderChainConfig returns the config.json of the C-Chain of the nodes, nil if cChain.config isn't given
+func RenderChainConfig(spec *chainv1alpha1.AvalanchegoSpec) *CChainConfig {
+	if spec.CChain == nil || spec.CChain.Config == nil {
+		return nil
+	}
+	c := spec.CChain.Config
+	return &CChainConfig{
+		LogLevel:          c.LogLevel,
+		PruningEnabled:    c.PruningEnabled,
+		LocalTxsEnabled:   c.LocalTxsEnabled,
+		SnowmanAPIEnabled: c.SnowmanAPIEnabled,
+		RPCGasCap:         c.RPCGasCap,
+	}
+}
+
+// renderCChainGenesis applies the spec to a C-Chain genesis
+func renderCChainGenesis(c *chainv1alpha1.CChainSpec, g *CChainGenesis) error {
+	if c.ChainID != 0 {
+		g.Config.ChainID = c.ChainID
+	}
+	if c.GasLimit != 0 {
+		g.GasLimit = "0x" + strconv.FormatInt(c.GasLimit, 16)
+	}
+	if len(c.Alloc) > 0 {
+		g.Alloc = make(map[string]CChainAccount, len(c.Alloc))
+		for address, account := range c.Alloc {
+			balance, ok := new(big.Int).SetString(account.Balance, 0)
+			if !ok {
+				return fmt.Errorf("invalid balance %q of %s", account.Balance, address)
+			}
+			g.Alloc[strings.TrimPrefix(address, "0x")] = CChainAccount{Balance: "0x" + balance.Text(16)}
+		}
+	}
+	if f := c.FeeConfig; f != nil {
+		gasLimit, _ := strconv.ParseInt(strings.TrimPrefix(g.GasLimit, "0x"), 16, 64)
+		g.Config.FeeConfig = &CChainFeeConfig{
+			GasLimit:                 gasLimit,
+			TargetBlockRate:          f.TargetBlockRate,
+			MinBaseFee:               f.MinBaseFee,
+			TargetGas:                f.TargetGas,
+			BaseFeeChangeDenominator: f.BaseFeeChangeDenominator,
+			MinBlockGasCost:          f.MinBlockGasCost,
+			MaxBlockGasCost:          f.MaxBlockGasCost,
+			BlockGasCostStep:         f.BlockGasCostStep,
+		}
+	}
+	g.Config.ContractDeployerAllowListConfig = precompileConfig(c.Precompiles.ContractDeployerAllowList)
+	g.Config.TxAllowListConfig = precompileConfig(c.Precompiles.TxAllowList)
+	g.Config.ContractNativeMinterConfig = precompileConfig(c.Precompiles.ContractNativeMinter)
+	g.Config.FeeManagerConfig = precompileConfig(c.Precompiles.FeeManager)
+	return nil
+}
+
+// precompileConfig returns the config enabling a precompile from the genesis block, nil if it isn't enabled
+func precompileConfig(a *chainv1alpha1.CChainAllowList) *CChainPrecompileConfig {
+	if a == nil {
+		return nil
+	}
+	return &CChainPrecompileConfig{
+		AdminAddresses:   a.AdminAddresses,
+		EnabledAddresses: a.EnabledAddresses,
+	}
+}
@@ -0,0 +1,88 @@
+package common
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+)
+
+var _ = Describe("Rendering", func() {
+	It("Should start generated genesis before the creation of the network", func() {
+		createdAt := time.Unix(1700000000, 0)
+		spec := chainv1alpha1.AvalanchegoSpec{NodeCount: 1}
+		g, err := RenderGenesis(&spec, createdAt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(g.StartTime).Should(Equal(1700000000 - 3600))
+
+		spec.GenesisTemplate = &chainv1alpha1.GenesisTemplate{StartTimeOffset: &metav1.Duration{Duration: 10 * time.Minute}}
+		g, err = RenderGenesis(&spec, createdAt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(g.StartTime).Should(Equal(1700000000 - 600))
+
+		spec.GenesisTemplate = &chainv1alpha1.GenesisTemplate{StartTime: 1600000000}
+		g, err = RenderGenesis(&spec, createdAt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(g.StartTime).Should(Equal(1600000000))
+	})
+
+	It("Should render the C-Chain into the genesis", func() {
+		spec := chainv1alpha1.AvalanchegoSpec{
+			NodeCount: 1,
+			CChain: &chainv1alpha1.CChainSpec{
+				ChainID:     99999,
+				GasLimit:    8000000,
+				Alloc:       map[string]chainv1alpha1.CChainAccount{"0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC": {Balance: "1000000000000000000"}},
+				Precompiles: chainv1alpha1.CChainPrecompiles{ContractNativeMinter: &chainv1alpha1.CChainAllowList{EnabledAddresses: []string{"0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC"}}},
+			},
+		}
+		g, err := RenderGenesis(&spec, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		var cChain CChainGenesis
+		Expect(json.Unmarshal([]byte(g.CChainGenesis), &cChain)).Should(Succeed())
+		Expect(cChain.Config.ChainID).Should(Equal(int64(99999)))
+		Expect(cChain.GasLimit).Should(Equal("0x7a1200"))
+		Expect(cChain.Alloc).Should(Equal(map[string]CChainAccount{"8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC": {Balance: "0xde0b6b3a7640000"}}))
+		Expect(cChain.Config.ContractNativeMinterConfig).Should(Equal(&CChainPrecompileConfig{EnabledAddresses: []string{"0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC"}}))
+		Expect(cChain.Config.TxAllowListConfig).Should(BeNil())
+	})
+
+	It("Should render genesis templates over the default genesis", func() {
+		fee := 20000
+		spec := chainv1alpha1.AvalanchegoSpec{
+			NodeCount: 3,
+			NetworkID: 1000,
+			GenesisTemplate: &chainv1alpha1.GenesisTemplate{
+				Message: "hello",
+				Stakers: []chainv1alpha1.GenesisStaker{
+					{RewardAddress: "X-custom1g65uqn6t77p656w64023nh8nd9updzmxwd59gh"},
+					{DelegationFee: &fee},
+				},
+			},
+		}
+		g, err := RenderGenesis(&spec, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(g.NetworkID).Should(Equal(1000))
+		Expect(g.Message).Should(Equal("hello"))
+		Expect(g.Allocations).Should(HaveLen(3))
+		Expect(g.InitialStakeDuration).Should(Equal(chainv1alpha1.DefaultGenesisInitialStakeDuration))
+		Expect(g.InitialStakedFunds).Should(Equal([]string{chainv1alpha1.DefaultGenesisStakedFunds}))
+		Expect(g.InitialStakers).Should(Equal([]InitialStaker{
+			{RewardAddress: "X-custom1g65uqn6t77p656w64023nh8nd9updzmxwd59gh", DelegationFee: 5000},
+			{RewardAddress: DefaultRewardAddress, DelegationFee: 20000},
+			{RewardAddress: DefaultRewardAddress, DelegationFee: 20000},
+		}))
+	})
+
+	It("Should render the C-Chain config, only if given", func() {
+		spec := chainv1alpha1.AvalanchegoSpec{CChain: &chainv1alpha1.CChainSpec{ChainID: 99999}}
+		Expect(RenderChainConfig(&spec)).Should(BeNil())
+
+		spec.CChain.Config = &chainv1alpha1.CChainConfig{LogLevel: "debug"}
+		Expect(RenderChainConfig(&spec)).Should(Equal(&CChainConfig{LogLevel: "debug"}))
+	})
+})
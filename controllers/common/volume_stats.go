@@ -0,0 +1,137 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"k8s.io/client-go/rest"
+)
+
+// ErrNoVolumeStats is returned when no stats are available for a volume, e.g. its pod just started
+var ErrNoVolumeStats = errors.New("no stats available for the volume")
+
+// VolumeStatsSource reports the disk usage of the volumes of running pods
+type VolumeStatsSource interface {
+	// PVCStats returns the usage of the volume of a PVC, mounted by a pod running on the given k8s node
+	PVCStats(ctx context.Context, nodeName, namespace, pvcName string) (VolumeStats, error)
+}
+
+type VolumeStats struct {
+	CapacityBytes int64
+	UsedBytes     int64
+}
+
+// UsedPercent returns the share of the volume in use, in percent
+func (s VolumeStats) UsedPercent() int {
+	if s.CapacityBytes <= 0 {
+		return 0
+	}
+	return int(s.UsedBytes * 100 / s.CapacityBytes)
+}
+
+type kubeletVolumeStats struct {
+	client rest.Interface
+}
+
+// NewKubeletVolumeStats returns a VolumeStatsSource reading the stats summary of the kubelets,
+// through the node proxy of the API server. client is a REST client of the core API group.
+func NewKubeletVolumeStats(client rest.Interface) VolumeStatsSource {
+	return &kubeletVolumeStats{
+		client: client,
+	}
+}
+
+// statsSummary is the part of the kubelet stats summary holding the volumes of the pods
+type statsSummary struct {
+	Pods []struct {
+		Volumes []struct {
+			CapacityBytes *int64 `json:"capacityBytes"`
+			UsedBytes     *int64 `json:"usedBytes"`
+			PVCRef        *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+func (s *kubeletVolumeStats) PVCStats(ctx context.Context, nodeName, namespace, pvcName string) (VolumeStats, error) {
+	raw, err := s.client.Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy").
+		Suffix("stats/summary").
+		Do(ctx).
+		Raw()
+	if err != nil {
+		return VolumeStats{}, fmt.Errorf("couldn't get the stats summary of node %s: %w", nodeName, err)
+	}
+	var summary statsSummary
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return VolumeStats{}, fmt.Errorf("couldn't decode the stats summary of node %s: %w", nodeName, err)
+	}
+
+	for _, pod := range summary.Pods {
+		for _, volume := range pod.Volumes {
+			if volume.PVCRef == nil || volume.PVCRef.Name != pvcName || volume.PVCRef.Namespace != namespace {
+				continue
+			}
+			if volume.CapacityBytes == nil || volume.UsedBytes == nil {
+				return VolumeStats{}, ErrNoVolumeStats
+			}
+			return VolumeStats{
+				CapacityBytes: *volume.CapacityBytes,
+				UsedBytes:     *volume.UsedBytes,
+			}, nil
+		}
+	}
+	return VolumeStats{}, ErrNoVolumeStats
+}
+
+// StaticVolumeStats is a VolumeStatsSource returning stats set beforehand, for testing without kubelets
+type StaticVolumeStats struct {
+	mu    sync.Mutex
+	stats map[string]VolumeStats
+}
+
+func NewStaticVolumeStats() *StaticVolumeStats {
+	return &StaticVolumeStats{
+		stats: map[string]VolumeStats{},
+	}
+}
+
+// Set sets the stats returned for a PVC, on any k8s node
+func (s *StaticVolumeStats) Set(namespace, pvcName string, stats VolumeStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats[namespace+"/"+pvcName] = stats
+}
+
+func (s *StaticVolumeStats) PVCStats(ctx context.Context, nodeName, namespace, pvcName string) (VolumeStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.stats[namespace+"/"+pvcName]
+	if !ok {
+		return VolumeStats{}, ErrNoVolumeStats
+	}
+	return stats, nil
+}
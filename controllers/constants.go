@@ -17,3 +17,6 @@ limitations under the License.
 package controllers
 
 const avaGoPrefix = "avago-"
+
+// deploymentLabel is set on the pods of all the nodes of an Avalanchego object, to the deployment name
+const deploymentLabel = "chain.djtx.network/deployment"
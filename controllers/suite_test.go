@@ -20,12 +20,13 @@ import (
 	"context"
 	"path/filepath"
 	"testing"
+	"time"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -34,6 +35,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
+	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,6 +47,12 @@ var testEnv *envtest.Environment
 var ctx context.Context
 var cancel context.CancelFunc
 
+// volumeStats stands in for the kubelets, which don't run in the test environment
+var volumeStats = common.NewStaticVolumeStats()
+
+// testClock is stepped by the tests to reach backup schedules and upgrade deadlines
+var testClock = clock.NewFakeClock(time.Now())
+
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
 
@@ -59,7 +67,8 @@ var _ = BeforeSuite(func() {
 
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
+		// The VolumeSnapshot CRD of the external snapshotter is installed for backups
+		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases"), filepath.Join("testdata", "crds")},
 		ErrorIfCRDPathMissing: true,
 	}
 
@@ -82,8 +91,12 @@ var _ = BeforeSuite(func() {
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&AvalanchegoReconciler{
-		Client: k8sManager.GetClient(),
-		Scheme: k8sManager.GetScheme(),
+		Client:      k8sManager.GetClient(),
+		Scheme:      k8sManager.GetScheme(),
+		NodeAPI:     common.NewNodeAPIClient(time.Second),
+		Recorder:    k8sManager.GetEventRecorderFor("avalanchego-controller"),
+		VolumeStats: volumeStats,
+		Clock:       testClock,
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
@@ -92,12 +105,6 @@ var _ = BeforeSuite(func() {
 		err = k8sManager.Start(ctx)
 		Expect(err).ToNot(HaveOccurred(), "failed to run manager")
 	}()
-	// Saying that this is a test run (mainly needed for ensure statefulset event watcher)
-	isTestRun = true
-	// Setting up eventsWatcherClientSet so that we watch the internal events
-	eventsWatcherClientSet, err = kubernetes.NewForConfig(k8sManager.GetConfig())
-	Expect(err).ToNot(HaveOccurred(), "failed to create eventsWatcherClientSet")
-
 }, 60)
 
 var _ = AfterSuite(func() {
@@ -17,15 +17,20 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -33,6 +38,7 @@ import (
 
 	chainv1alpha1 "github.com/lasthyphen/dijetsgo-operator/api/v1alpha1"
 	"github.com/lasthyphen/dijetsgo-operator/controllers"
+	"github.com/lasthyphen/dijetsgo-operator/controllers/common"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -48,15 +54,26 @@ func init() {
 	//+kubebuilder:scaffold:scheme
 }
 
+// The operator binary can also write the bootstrap config file of nodes it doesn't manage, see runBootstrapResolver
+const bootstrapResolverCommand = "bootstrap-resolver"
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == bootstrapResolverCommand {
+		os.Exit(runBootstrapResolver(os.Args[2:]))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var syncPeriod time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&syncPeriod, "sync-period", 10*time.Minute,
+		"How often every Avalanchego object is reconciled, even if nothing changed. "+
+			"Drift in owned objects is usually corrected right away, the periodic reconciliation is a fallback.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -72,19 +89,37 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "ecfcc342.djtx.network",
+		SyncPeriod:             &syncPeriod,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create clientset")
+		os.Exit(1)
+	}
+
 	if err := (&controllers.AvalanchegoReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		NodeAPI:     common.NewNodeAPIClient(5 * time.Second),
+		Recorder:    mgr.GetEventRecorderFor("avalanchego-controller"),
+		VolumeStats: common.NewKubeletVolumeStats(clientset.CoreV1().RESTClient()),
+		Clock:       clock.RealClock{},
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Avalanchego")
 		os.Exit(1)
 	}
+	// Webhooks need serving certificates, set ENABLE_WEBHOOKS=false to run the operator locally without them
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&chainv1alpha1.Avalanchego{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Avalanchego")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -102,3 +137,57 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// runBootstrapResolver writes the IPs and NodeIDs of the bootstrappers into the config file of a node
+func runBootstrapResolver(args []string) int {
+	fs := flag.NewFlagSet(bootstrapResolverCommand, flag.ExitOnError)
+	bootstrappers := fs.String("bootstrappers", os.Getenv("BOOTSTRAPPERS"),
+		"Comma separated hostnames or IPs of the bootstrappers, every address of a hostname is a bootstrapper.")
+	configFile := fs.String("config-file", "/tmp/conf/conf.json", "The avalanchego config file to write.")
+	timeout := fs.Duration("timeout", 10*time.Minute,
+		"How long to wait for the bootstrappers. Once it passes, the ones found so far are used, it fails if there are none.")
+	requestTimeout := fs.Duration("request-timeout", 5*time.Second, "Timeout of a single request to a bootstrapper.")
+	httpPort := fs.Int("http-port", 9650, "Port of the HTTP API of the bootstrappers.")
+	stakingPort := fs.Int("staking-port", 9651, "Staking port of the bootstrappers.")
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(fs)
+	_ = fs.Parse(args)
+
+	log := zap.New(zap.UseFlagOptions(&opts)).WithName(bootstrapResolverCommand)
+
+	var hosts []string
+	for _, host := range strings.Split(*bootstrappers, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		log.Error(nil, "no bootstrappers given")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(ctrl.SetupSignalHandler(), *timeout)
+	defer cancel()
+	resolver := &common.BootstrapResolver{
+		NodeAPI:     common.NewNodeAPIClient(*requestTimeout),
+		HTTPPort:    *httpPort,
+		StakingPort: *stakingPort,
+		MinBackoff:  time.Second,
+		MaxBackoff:  30 * time.Second,
+		Log:         log,
+	}
+	found, err := resolver.ResolveAll(ctx, hosts)
+	if err != nil {
+		log.Error(err, "unable to find the bootstrappers")
+		return 1
+	}
+	config := common.NewBootstrapConfig(found)
+	if err := common.WriteBootstrapConfig(*configFile, config); err != nil {
+		log.Error(err, "unable to write the config file", "path", *configFile)
+		return 1
+	}
+	log.Info("Config file written", "path", *configFile, "bootstrap-ips", config.BootstrapIPs, "bootstrap-ids", config.BootstrapIDs)
+	return 0
+}